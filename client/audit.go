@@ -0,0 +1,392 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAuditMethods is the default list of audited request methods.
+var DefaultAuditMethods = []string{
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+const (
+	// maxAuditAuthBodySize is the maximum size of an authentication
+	// response body to be parsed for the token identity.
+	maxAuditAuthBodySize = 4 * 1024 * 1024
+
+	// maxAuditIdentities is the maximum number of token identities to be
+	// remembered.
+	maxAuditIdentities = 64
+)
+
+// sensitiveQueryParams is a list of query parameters, which are masked in
+// the audited URLs in addition to the sensitive fields.
+var sensitiveQueryParams = []string{
+	"temp_url_sig",
+	"signature",
+}
+
+// AuditIdentity represents the user and the scope of a token.
+type AuditIdentity struct {
+	UserID                  string `json:"user_id,omitempty"`
+	UserName                string `json:"user_name,omitempty"`
+	ProjectID               string `json:"project_id,omitempty"`
+	ProjectName             string `json:"project_name,omitempty"`
+	DomainID                string `json:"domain_id,omitempty"`
+	DomainName              string `json:"domain_name,omitempty"`
+	ApplicationCredentialID string `json:"application_credential_id,omitempty"`
+}
+
+// AuditRecord represents an audited request. The URL and the body are masked
+// the same way as in the debug log. Bodies, which cannot be masked, e.g.
+// binary or truncated JSON bodies, are omitted.
+type AuditRecord struct {
+	Time          time.Time     `json:"time"`
+	Identity      AuditIdentity `json:"identity"`
+	Method        string        `json:"method"`
+	URL           string        `json:"url"`
+	Body          string        `json:"body,omitempty"`
+	BodyTruncated bool          `json:"body_truncated,omitempty"`
+	StatusCode    int           `json:"status_code,omitempty"`
+	RequestID     string        `json:"request_id,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// AuditSink stores audit records.
+type AuditSink interface {
+	WriteAuditRecord(record AuditRecord) error
+}
+
+// AuditLog records requests, which modify resources, in an AuditSink. It is
+// independent of the debug logger. The identity of a token is learned from
+// the authentication responses passing the RoundTripper. An AuditLog must
+// not be copied after first use.
+type AuditLog struct {
+	// Sink stores the audit records.
+	Sink AuditSink
+
+	// Methods is a list of audited request methods. If nil,
+	// DefaultAuditMethods is used.
+	Methods []string
+
+	// Identity returns the identity of a token, which has not been
+	// obtained through the RoundTripper.
+	Identity func(token string) (AuditIdentity, bool)
+
+	// OnError is called, when the Sink fails to store a record. If nil,
+	// the error is logged with the standard logger.
+	OnError func(record AuditRecord, err error)
+
+	mu         sync.Mutex
+	identities map[[sha256.Size]byte]AuditIdentity
+}
+
+func (a *AuditLog) audited(request *http.Request) bool {
+	methods := a.Methods
+	if methods == nil {
+		methods = DefaultAuditMethods
+	}
+	return slices.Contains(methods, request.Method)
+}
+
+// identity returns the identity of a token.
+func (a *AuditLog) identity(token string) AuditIdentity {
+	if token == "" {
+		return AuditIdentity{}
+	}
+
+	a.mu.Lock()
+	identity, ok := a.identities[sha256.Sum256([]byte(token))]
+	a.mu.Unlock()
+	if ok {
+		return identity
+	}
+
+	if a.Identity != nil {
+		if identity, ok := a.Identity(token); ok {
+			return identity
+		}
+	}
+
+	return AuditIdentity{}
+}
+
+// learn stores the identity of a token. The token is hashed to avoid keeping
+// it in memory.
+func (a *AuditLog) learn(token string, identity AuditIdentity) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.identities == nil || len(a.identities) >= maxAuditIdentities {
+		a.identities = make(map[[sha256.Size]byte]AuditIdentity)
+	}
+	a.identities[sha256.Sum256([]byte(token))] = identity
+}
+
+func (a *AuditLog) write(record AuditRecord) {
+	err := a.Sink.WriteAuditRecord(record)
+	if err == nil {
+		return
+	}
+
+	if a.OnError != nil {
+		a.OnError(record, err)
+		return
+	}
+
+	log.Printf("[ERROR] unable to write the audit record of %s %s: %s", record.Method, record.URL, err)
+}
+
+// authEntity represents an entity of an authentication response.
+type authEntity struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Domain *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"domain"`
+}
+
+// authResponse represents an Identity v2 or v3 authentication response.
+type authResponse struct {
+	Token *struct {
+		User                  authEntity  `json:"user"`
+		Project               *authEntity `json:"project"`
+		Domain                *authEntity `json:"domain"`
+		ApplicationCredential *authEntity `json:"application_credential"`
+	} `json:"token"`
+	Access *struct {
+		Token struct {
+			ID     string      `json:"id"`
+			Tenant *authEntity `json:"tenant"`
+		} `json:"token"`
+		User authEntity `json:"user"`
+	} `json:"access"`
+}
+
+// parseAuthResponse returns the token and its identity of an authentication
+// response body.
+func parseAuthResponse(header http.Header, body []byte) (string, AuditIdentity, bool) {
+	var r authResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return "", AuditIdentity{}, false
+	}
+
+	var identity AuditIdentity
+	switch {
+	case r.Token != nil:
+		token := header.Get("X-Subject-Token")
+		if token == "" {
+			return "", identity, false
+		}
+
+		identity.UserID = r.Token.User.ID
+		identity.UserName = r.Token.User.Name
+		if p := r.Token.Project; p != nil {
+			identity.ProjectID = p.ID
+			identity.ProjectName = p.Name
+			if p.Domain != nil {
+				identity.DomainID = p.Domain.ID
+				identity.DomainName = p.Domain.Name
+			}
+		}
+		if d := r.Token.Domain; d != nil {
+			identity.DomainID = d.ID
+			identity.DomainName = d.Name
+		}
+		if ac := r.Token.ApplicationCredential; ac != nil {
+			identity.ApplicationCredentialID = ac.ID
+		}
+
+		return token, identity, true
+	case r.Access != nil:
+		if r.Access.Token.ID == "" {
+			return "", identity, false
+		}
+
+		identity.UserID = r.Access.User.ID
+		identity.UserName = r.Access.User.Name
+		if t := r.Access.Token.Tenant; t != nil {
+			identity.ProjectID = t.ID
+			identity.ProjectName = t.Name
+		}
+
+		return r.Access.Token.ID, identity, true
+	}
+
+	return "", identity, false
+}
+
+// isAuthResponse reports whether the response may contain a token and its
+// identity.
+func isAuthResponse(request *http.Request, response *http.Response) bool {
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return false
+	}
+
+	if response.Header.Get("X-Subject-Token") != "" {
+		return true
+	}
+
+	return request.Method == http.MethodPost && strings.HasSuffix(strings.TrimSuffix(request.URL.Path, "/"), "/tokens")
+}
+
+// maskURL masks the credentials and the sensitive query parameters of a URL.
+func (rt *RoundTripper) maskURL(u *url.URL) string {
+	masked := *u
+	if masked.User != nil {
+		masked.User = url.User("***")
+	}
+
+	if masked.RawQuery != "" {
+		query := masked.Query()
+		for k, values := range query {
+			if rt.isSensitiveField(k) || slices.Contains(sensitiveQueryParams, strings.ToLower(k)) {
+				for i := range values {
+					values[i] = "***"
+				}
+			}
+		}
+		masked.RawQuery = query.Encode()
+	}
+
+	return masked.String()
+}
+
+// auditStart starts an audit record of a request. It returns nil, when the
+// request is not audited.
+func (rt *RoundTripper) auditStart(audit *AuditLog, request *http.Request) (*AuditRecord, error) {
+	if !audit.audited(request) {
+		return nil, nil
+	}
+
+	record := &AuditRecord{
+		Time:     time.Now().UTC(),
+		Identity: audit.identity(request.Header.Get("X-Auth-Token")),
+		Method:   request.Method,
+		URL:      rt.maskURL(request.URL),
+	}
+
+	if request.Body != nil && request.Body != http.NoBody {
+		contentType := request.Header.Get("Content-Type")
+		text, body, truncated, err := rt.maskedBody(request.Body, contentType)
+		if err != nil {
+			return nil, err
+		}
+		request.Body = body
+
+		if getBodyKind(contentType) == bodyJSON {
+			var buf bytes.Buffer
+			if json.Compact(&buf, []byte(text)) == nil {
+				text = buf.String()
+			}
+		}
+		record.Body = text
+		record.BodyTruncated = truncated
+	}
+
+	return record, nil
+}
+
+// auditFinish learns the token identity of an authentication response and
+// writes the audit record of the request.
+func (rt *RoundTripper) auditFinish(audit *AuditLog, record *AuditRecord, request *http.Request, response *http.Response, err error) (*http.Response, error) {
+	if response != nil && isAuthResponse(request, response) {
+		prefix, body, truncated, bodyErr := readBodyPrefix(response.Body, maxAuditAuthBodySize)
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		response.Body = body
+
+		if !truncated {
+			if token, identity, ok := parseAuthResponse(response.Header, prefix); ok {
+				audit.learn(token, identity)
+				if record != nil && record.Identity == (AuditIdentity{}) {
+					record.Identity = identity
+				}
+			}
+		}
+	}
+
+	if record == nil {
+		return response, err
+	}
+
+	record.RequestID = request.Header.Get(requestIDHeader)
+	if response != nil {
+		record.StatusCode = response.StatusCode
+		if id := responseRequestID(response); id != "" {
+			record.RequestID = id
+		}
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	audit.write(*record)
+
+	return response, err
+}
+
+// JSONLinesAuditSink writes audit records as JSON lines.
+type JSONLinesAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesAuditSink returns a new JSONLinesAuditSink, which writes to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{w: w}
+}
+
+// OpenJSONLinesAuditFile opens or creates the file, which audit records are
+// appended to.
+func OpenJSONLinesAuditFile(name string) (*JSONLinesAuditSink, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONLinesAuditSink(f), nil
+}
+
+// WriteAuditRecord writes the record as a single line.
+func (s *JSONLinesAuditSink) WriteAuditRecord(record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(data)
+	return err
+}
+
+// Close closes the underlying writer, if it is an io.Closer.
+func (s *JSONLinesAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesAuditSink(&buf)
+
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Path, "/auth/tokens") {
+				response := newTestResponse(r, http.StatusCreated, http.Header{
+					"X-Subject-Token": {"token1"},
+					"Content-Type":    {"application/json"},
+				})
+				response.Body = io.NopCloser(strings.NewReader(`{"token": {
+					"user": {"id": "u1", "name": "admin"},
+					"project": {"id": "p1", "name": "demo", "domain": {"id": "default", "name": "Default"}},
+					"application_credential": {"id": "ac1", "name": "automation"}
+				}}`))
+				return response, nil
+			}
+			return newTestResponse(r, http.StatusAccepted, http.Header{
+				"X-Openstack-Request-Id": {"req-1"},
+			}), nil
+		}),
+		Audit: &AuditLog{Sink: sink},
+	}
+
+	body := `{"auth": {"identity": {"methods": ["application_credential"], "application_credential": {"id": "ac1", "secret": "s3cr3t"}}}}`
+	request, _ := http.NewRequest("POST", "http://identity.example.com/v3/auth/tokens", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	_, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+
+	for _, method := range []string{"GET", "PUT"} {
+		request, _ = http.NewRequest(method, "http://compute.example.com/servers/1?password=s3cr3t", strings.NewReader(`{"server": {"adminPass": "s3cr3t", "name": "test"}}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Auth-Token", "token1")
+		_, err = rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+	}
+
+	if strings.Contains(buf.String(), "s3cr3t") || strings.Contains(buf.String(), "token1") {
+		t.Fatalf("audit log contains secrets: %s", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	th.AssertEquals(t, 2, len(lines))
+
+	identity := AuditIdentity{
+		UserID:                  "u1",
+		UserName:                "admin",
+		ProjectID:               "p1",
+		ProjectName:             "demo",
+		DomainID:                "default",
+		DomainName:              "Default",
+		ApplicationCredentialID: "ac1",
+	}
+
+	var record AuditRecord
+	th.AssertNoErr(t, json.Unmarshal([]byte(lines[0]), &record))
+	th.AssertEquals(t, "POST", record.Method)
+	th.AssertEquals(t, http.StatusCreated, record.StatusCode)
+	th.AssertDeepEquals(t, identity, record.Identity)
+
+	record = AuditRecord{}
+	th.AssertNoErr(t, json.Unmarshal([]byte(lines[1]), &record))
+	th.AssertEquals(t, "PUT", record.Method)
+	th.AssertEquals(t, "http://compute.example.com/servers/1?password=%2A%2A%2A", record.URL)
+	th.AssertEquals(t, `{"server":{"adminPass":"***","name":"test"}}`, record.Body)
+	th.AssertEquals(t, http.StatusAccepted, record.StatusCode)
+	th.AssertEquals(t, "req-1", record.RequestID)
+	th.AssertDeepEquals(t, identity, record.Identity)
+}
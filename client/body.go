@@ -0,0 +1,286 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxLogBodySize is the default maximum size of a request or response
+// body to be logged.
+const DefaultMaxLogBodySize = 64 * 1024
+
+// bodyKind represents the way a body is logged.
+type bodyKind int
+
+const (
+	bodyBinary bodyKind = iota
+	bodyJSON
+	bodyText
+	bodyForm
+)
+
+// sensitiveFieldNames is a list of substrings, which mark form fields and
+// text keys as sensitive in addition to the Redactor key rules.
+var sensitiveFieldNames = []string{
+	"password",
+	"secret",
+	"token",
+}
+
+// getBodyKind detects the way a body is logged based on its Content-Type.
+func getBodyKind(contentType string) bodyKind {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		v, _, _ := strings.Cut(contentType, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	switch {
+	case mediaType == "application/json",
+		strings.HasPrefix(mediaType, "application/") && (strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "-json-patch")):
+		return bodyJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return bodyForm
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/xml",
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/yaml",
+		mediaType == "application/x-yaml":
+		return bodyText
+	}
+
+	return bodyBinary
+}
+
+// logBody logs a request or response body according to its Content-Type.
+// JSON, text and form bodies are read up to the maximum log body size,
+// masked and logged. Larger bodies are logged truncated, the rest of the
+// body is streamed without buffering. Any other body is never buffered, its
+// length and checksum are logged once it has been consumed.
+func (rt *RoundTripper) logBody(kind string, original io.ReadCloser, contentType string) (io.ReadCloser, error) {
+	if original == http.NoBody {
+		return original, nil
+	}
+
+	bk := getBodyKind(contentType)
+	if bk == bodyBinary {
+		return newSummaryReader(original, func(n int64, sum []byte) {
+			rt.log().Printf("OpenStack %s Body: binary data, %d bytes, sha256 %x", kind, n, sum)
+		}), nil
+	}
+
+	prefix, body, truncated, err := readBodyPrefix(original, rt.maxLogBodySize())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prefix) == 0 {
+		return body, nil
+	}
+
+	if bk == bodyJSON && truncated {
+		// partial JSON documents cannot be masked reliably
+		rt.log().Printf("OpenStack %s Body: JSON body exceeds %d bytes, not logging", kind, len(prefix))
+		return body, nil
+	}
+
+	debugInfo, err := rt.formatBody(bk, prefix)
+	if err != nil {
+		rt.log().Printf("%s", err)
+	}
+
+	if truncated {
+		debugInfo = fmt.Sprintf("%s\n... (truncated to %d bytes)", debugInfo, len(prefix))
+	}
+
+	if debugInfo != "" {
+		rt.log().Printf("OpenStack %s Body: %s", kind, debugInfo)
+	}
+
+	return body, nil
+}
+
+// formatBody formats and masks a body of the specified kind.
+func (rt *RoundTripper) formatBody(bk bodyKind, raw []byte) (string, error) {
+	switch bk {
+	case bodyJSON:
+		return rt.formatJSON()(raw)
+	case bodyForm:
+		return rt.maskForm(string(raw)), nil
+	case bodyText:
+		return rt.maskText(string(raw)), nil
+	}
+	return "", nil
+}
+
+// maskedBody returns the masked text of a JSON, text or form body, which is
+// read up to the maximum log body size, and a body, which yields the
+// complete original content. Binary bodies, truncated JSON bodies and
+// bodies, which cannot be parsed, are not returned as text.
+func (rt *RoundTripper) maskedBody(original io.ReadCloser, contentType string) (text string, body io.ReadCloser, truncated bool, err error) {
+	bk := getBodyKind(contentType)
+	if original == nil || original == http.NoBody || bk == bodyBinary {
+		return "", original, false, nil
+	}
+
+	prefix, body, truncated, err := readBodyPrefix(original, rt.maxLogBodySize())
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	if bk == bodyJSON && truncated {
+		return "", body, truncated, nil
+	}
+
+	text, err = rt.formatBody(bk, prefix)
+	if err != nil {
+		// the body may contain sensitive data, which cannot be masked
+		return "", body, truncated, nil
+	}
+
+	return text, body, truncated, nil
+}
+
+func (rt *RoundTripper) maxLogBodySize() int64 {
+	// this is concurrency safe
+	v := rt.MaxLogBodySize
+	if v <= 0 {
+		return DefaultMaxLogBodySize
+	}
+	return v
+}
+
+// isSensitiveField reports whether a form field or a text key contains
+// sensitive data.
+func (rt *RoundTripper) isSensitiveField(name string) bool {
+	v := strings.ToLower(name)
+	for _, s := range sensitiveFieldNames {
+		if strings.Contains(v, s) {
+			return true
+		}
+	}
+	return rt.redactor().MatchKey(name)
+}
+
+// maskForm masks sensitive fields of an URL encoded form.
+func (rt *RoundTripper) maskForm(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		// the body may be truncated, mask the fields which could be parsed
+		rt.log().Printf("unable to parse OpenStack form: %s", err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(values))
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if rt.isSensitiveField(k) {
+				v = "***"
+			}
+			result = append(result, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	return strings.Join(result, "&")
+}
+
+// maskText masks the values of "key=value" and "key: value" lines, which
+// keys are sensitive.
+func (rt *RoundTripper) maskText(raw string) string {
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		idx := strings.IndexAny(line, "=:")
+		if idx <= 0 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(line[:idx]), `"'`)
+		if key != "" && rt.isSensitiveField(key) {
+			lines[i] = line[:idx+1] + " ***"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readBodyPrefix reads up to limit bytes of the original body. It returns
+// the read prefix and a body, which yields the complete original content.
+// truncated is true, when the original body is larger than limit.
+func readBodyPrefix(original io.ReadCloser, limit int64) (prefix []byte, body io.ReadCloser, truncated bool, err error) {
+	prefix, err = io.ReadAll(io.LimitReader(original, limit+1))
+	if err != nil {
+		original.Close()
+		return nil, nil, false, err
+	}
+
+	if int64(len(prefix)) <= limit {
+		original.Close()
+		return prefix, io.NopCloser(bytes.NewReader(prefix)), false, nil
+	}
+
+	body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(prefix), original),
+		Closer: original,
+	}
+
+	return prefix[:limit], body, true, nil
+}
+
+// readCloser combines an io.Reader and an io.Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// summaryReader counts and hashes a body while it is being consumed and
+// calls the done function once the body is read to the end or closed.
+type summaryReader struct {
+	rc   io.ReadCloser
+	hash hash.Hash
+	n    int64
+	once sync.Once
+	done func(n int64, sum []byte)
+}
+
+func newSummaryReader(rc io.ReadCloser, done func(n int64, sum []byte)) *summaryReader {
+	return &summaryReader{
+		rc:   rc,
+		hash: sha256.New(),
+		done: done,
+	}
+}
+
+func (r *summaryReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		r.hash.Write(p[:n])
+		r.n += int64(n)
+	}
+	if err == io.EOF {
+		r.finish()
+	}
+	return n, err
+}
+
+func (r *summaryReader) Close() error {
+	r.finish()
+	return r.rc.Close()
+}
+
+func (r *summaryReader) finish() {
+	r.once.Do(func() {
+		r.done(r.n, r.hash.Sum(nil))
+	})
+}
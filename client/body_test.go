@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+func TestLogBodyForm(t *testing.T) {
+	logger := &testLogger{}
+	rt := RoundTripper{Logger: logger}
+
+	raw := "grant_type=client_credentials&client_secret=s3cr3t&scope=openstack"
+	body, err := rt.logRequest(io.NopCloser(strings.NewReader(raw)), "application/x-www-form-urlencoded")
+	th.AssertNoErr(t, err)
+
+	actual, err := io.ReadAll(body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, raw, string(actual))
+	th.AssertEquals(t, "OpenStack Request Body: client_secret=***&grant_type=client_credentials&scope=openstack", logger.String())
+}
+
+func TestLogBodyTextTruncated(t *testing.T) {
+	logger := &testLogger{}
+	rt := RoundTripper{Logger: logger, MaxLogBodySize: 16}
+
+	raw := "password: hunter2\nsome long text which is truncated"
+	body, err := rt.logResponse(io.NopCloser(strings.NewReader(raw)), "text/plain; charset=utf-8")
+	th.AssertNoErr(t, err)
+
+	actual, err := io.ReadAll(body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, raw, string(actual))
+	th.AssertEquals(t, "OpenStack Response Body: password: ***\n... (truncated to 16 bytes)", logger.String())
+}
+
+func TestLogBodyJSONTooLarge(t *testing.T) {
+	logger := &testLogger{}
+	rt := RoundTripper{Logger: logger, MaxLogBodySize: 8}
+
+	raw := `{"user": {"password": "secret"}}`
+	body, err := rt.logRequest(io.NopCloser(strings.NewReader(raw)), "application/json")
+	th.AssertNoErr(t, err)
+
+	actual, err := io.ReadAll(body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, raw, string(actual))
+	th.AssertEquals(t, "OpenStack Request Body: JSON body exceeds 8 bytes, not logging", logger.String())
+}
+
+func TestLogBodyBinary(t *testing.T) {
+	logger := &testLogger{}
+	rt := RoundTripper{Logger: logger, MaxLogBodySize: 1}
+
+	body, err := rt.logResponse(io.NopCloser(strings.NewReader("hello")), "application/octet-stream")
+	th.AssertNoErr(t, err)
+
+	// nothing is logged until the body is consumed
+	th.AssertEquals(t, "", logger.String())
+
+	actual, err := io.ReadAll(body)
+	th.AssertNoErr(t, err)
+	th.AssertNoErr(t, body.Close())
+	th.AssertEquals(t, "hello", string(actual))
+	th.AssertEquals(t, "OpenStack Response Body: binary data, 5 bytes, sha256 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", logger.String())
+
+	body, err = rt.logResponse(http.NoBody, "")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.NoBody, body)
+}
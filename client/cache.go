@@ -0,0 +1,315 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheMaxBodySize is the default maximum size of a cached
+	// response body.
+	DefaultCacheMaxBodySize = 1024 * 1024
+
+	// DefaultCacheMaxEntries is the default maximum number of cached
+	// responses.
+	DefaultCacheMaxEntries = 1000
+)
+
+// CacheRule sets the time a response is considered fresh without
+// revalidation.
+type CacheRule struct {
+	// Pattern is a regular expression the request URL must match.
+	Pattern *regexp.Regexp
+
+	// TTL is the time a response is served from the cache without sending
+	// a request.
+	TTL time.Duration
+}
+
+// ResponseCache caches the responses of GET requests. Responses are keyed by
+// the URL, the token and the microversion headers. Stale responses with an
+// ETag or a Last-Modified header are revalidated using the If-None-Match and
+// If-Modified-Since headers. Write requests invalidate the cached responses
+// of the written resource, its subresources and its collection, both before
+// the request is sent and after a successful response. A ResponseCache must
+// not be copied after first use.
+type ResponseCache struct {
+	// Rules is a list of per URL pattern TTLs. The first matching rule
+	// applies.
+	Rules []CacheRule
+
+	// DefaultTTL is the TTL of responses not matching any rule. Responses
+	// without a TTL and without validators are not cached.
+	DefaultTTL time.Duration
+
+	// MaxBodySize is the maximum size of a cached response body. If zero,
+	// DefaultCacheMaxBodySize is used.
+	MaxBodySize int64
+
+	// MaxEntries is the maximum number of cached responses. If zero,
+	// DefaultCacheMaxEntries is used.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry represents a cached response.
+type cacheEntry struct {
+	key      string
+	url      *url.URL
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+func (c *ResponseCache) ttl(u string) time.Duration {
+	for _, rule := range c.Rules {
+		if rule.Pattern != nil && rule.Pattern.MatchString(u) {
+			return rule.TTL
+		}
+	}
+	return c.DefaultTTL
+}
+
+// cacheKey returns the cache key of a request. The token is hashed to
+// avoid keeping it in memory.
+func cacheKey(request *http.Request) string {
+	token := sha256.Sum256([]byte(request.Header.Get("X-Auth-Token")))
+
+	var versions []string
+	for k, v := range request.Header {
+		if strings.HasSuffix(strings.ToLower(k), "-api-version") {
+			versions = append(versions, strings.ToLower(k)+"="+strings.Join(v, ","))
+		}
+	}
+	sort.Strings(versions)
+
+	return strings.Join([]string{
+		request.URL.String(),
+		hex.EncodeToString(token[:]),
+		request.Header.Get("Accept"),
+		strings.Join(versions, ";"),
+	}, "\n")
+}
+
+// isCacheable reports whether the cache is applicable to the request.
+func isCacheable(request *http.Request) bool {
+	if request.Method != http.MethodGet {
+		return false
+	}
+
+	for _, h := range []string{"Range", "If-None-Match", "If-Modified-Since", "If-Match"} {
+		if request.Header.Get(h) != "" {
+			return false
+		}
+	}
+
+	return !strings.Contains(request.Header.Get("Cache-Control"), "no-cache")
+}
+
+// prepare returns a fresh cached response or sets the revalidation headers
+// of a stale cached response. It invalidates cached responses of write
+// requests.
+func (c *ResponseCache) prepare(request *http.Request) (*cacheEntry, *http.Response) {
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions:
+		return nil, nil
+	default:
+		c.invalidate(request.URL)
+		return nil, nil
+	}
+
+	if !isCacheable(request) {
+		return nil, nil
+	}
+
+	key := cacheKey(request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	if time.Since(e.storedAt) < c.ttl(request.URL.String()) {
+		return e, e.response(request)
+	}
+
+	etag := e.header.Get("ETag")
+	lastModified := e.header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil, nil
+	}
+
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return e, nil
+}
+
+// update stores a response or returns the cached response, when the stale
+// entry has been revalidated.
+func (c *ResponseCache) update(request *http.Request, e *cacheEntry, response *http.Response) (*http.Response, error) {
+	if request.Method != http.MethodGet {
+		// responses cached while the write was in progress may be stale
+		if request.Method != http.MethodHead && request.Method != http.MethodOptions &&
+			response.StatusCode >= 200 && response.StatusCode < 300 {
+			c.invalidate(request.URL)
+		}
+		return response, nil
+	}
+
+	if e != nil && response.StatusCode == http.StatusNotModified {
+		response.Body.Close()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		e.storedAt = time.Now()
+		for k, v := range response.Header {
+			if k == "Etag" || k == "Last-Modified" || k == "Date" {
+				e.header[k] = v
+			}
+		}
+
+		return e.response(request), nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return response, nil
+	}
+
+	hasValidators := response.Header.Get("ETag") != "" || response.Header.Get("Last-Modified") != ""
+	if !hasValidators && c.ttl(request.URL.String()) <= 0 {
+		return response, nil
+	}
+
+	if strings.Contains(response.Header.Get("Cache-Control"), "no-store") {
+		return response, nil
+	}
+
+	maxBodySize := c.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultCacheMaxBodySize
+	}
+
+	body, rc, truncated, err := readBodyPrefix(response.Body, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = rc
+	if truncated {
+		return response, nil
+	}
+
+	c.store(&cacheEntry{
+		key:      cacheKey(request),
+		url:      request.URL,
+		header:   response.Header.Clone(),
+		body:     body,
+		storedAt: time.Now(),
+	})
+
+	return response, nil
+}
+
+func (c *ResponseCache) store(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*cacheEntry)
+	}
+	c.entries[e.key] = e
+
+	maxEntries := c.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+
+	for len(c.entries) > maxEntries {
+		var oldest *cacheEntry
+		for _, v := range c.entries {
+			if oldest == nil || v.storedAt.Before(oldest.storedAt) {
+				oldest = v
+			}
+		}
+		delete(c.entries, oldest.key)
+	}
+}
+
+// invalidate removes the cached responses of a written resource, its
+// subresources and the collection it belongs to.
+func (c *ResponseCache) invalidate(u *url.URL) {
+	p := strings.TrimSuffix(u.Path, "/")
+	parent := path.Dir(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		if e.url.Host != u.Host {
+			continue
+		}
+
+		ep := strings.TrimSuffix(e.url.Path, "/")
+		if ep == p || strings.HasPrefix(ep, p+"/") || ep == parent || ep == parent+"/detail" {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Invalidate removes the cached responses, which URL starts with the
+// specified prefix.
+func (c *ResponseCache) Invalidate(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		if strings.HasPrefix(e.url.String(), prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Purge removes all cached responses.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// response returns a copy of the cached response. It must be called with
+// the cache lock held.
+func (e *cacheEntry) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestResponseCache(t *testing.T) {
+	var requests []string
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("If-None-Match"))
+
+			if r.Method != "GET" {
+				return newTestResponse(r, http.StatusAccepted, nil), nil
+			}
+
+			if strings.HasPrefix(r.URL.Path, "/v2/images") {
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					return newTestResponse(r, http.StatusNotModified, http.Header{"Etag": {`"v1"`}}), nil
+				}
+				response := newTestResponse(r, http.StatusOK, http.Header{"Etag": {`"v1"`}})
+				response.Body = io.NopCloser(strings.NewReader(`{"images": []}`))
+				return response, nil
+			}
+
+			response := newTestResponse(r, http.StatusOK, nil)
+			response.Body = io.NopCloser(strings.NewReader(`{"servers": []}`))
+			return response, nil
+		}),
+		Cache: &ResponseCache{
+			Rules: []CacheRule{
+				{Pattern: regexp.MustCompile(`/servers/detail$`), TTL: time.Minute},
+			},
+		},
+	}
+
+	get := func(u, token string) string {
+		request, _ := http.NewRequest("GET", u, nil)
+		request.Header.Set("X-Auth-Token", token)
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, http.StatusOK, response.StatusCode)
+		body, err := io.ReadAll(response.Body)
+		th.AssertNoErr(t, err)
+		return string(body)
+	}
+
+	// fresh responses are served from the cache
+	th.AssertEquals(t, `{"servers": []}`, get("http://compute.example.com/servers/detail", "token1"))
+	th.AssertEquals(t, `{"servers": []}`, get("http://compute.example.com/servers/detail", "token1"))
+	th.AssertDeepEquals(t, []string{"GET /servers/detail "}, requests)
+
+	// a different token scope is a cache miss
+	get("http://compute.example.com/servers/detail", "token2")
+	th.AssertEquals(t, 2, len(requests))
+
+	// responses with validators are revalidated
+	th.AssertEquals(t, `{"images": []}`, get("http://image.example.com/v2/images", "token1"))
+	th.AssertEquals(t, `{"images": []}`, get("http://image.example.com/v2/images", "token1"))
+	th.AssertDeepEquals(t, []string{"GET /v2/images ", `GET /v2/images "v1"`}, requests[2:])
+
+	// writes invalidate the collection
+	request, _ := http.NewRequest("DELETE", "http://compute.example.com/servers/1", nil)
+	request.Header.Set("X-Auth-Token", "token1")
+	_, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+
+	get("http://compute.example.com/servers/detail", "token1")
+	th.AssertDeepEquals(t, []string{"DELETE /servers/1 ", "GET /servers/detail "}, requests[4:])
+}
+
+func TestResponseCacheInvalidateWrite(t *testing.T) {
+	var rt *RoundTripper
+	var requests []string
+
+	get := func(u string) {
+		request, _ := http.NewRequest("GET", u, nil)
+		request.Header.Set("X-Auth-Token", "token1")
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		_, err = io.ReadAll(response.Body)
+		th.AssertNoErr(t, err)
+		response.Body.Close()
+	}
+
+	rt = &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+
+			if r.Method == "PUT" {
+				// a response cached while the write is in progress
+				get("http://compute.example.com/v2.1/servers/1")
+				return newTestResponse(r, http.StatusAccepted, nil), nil
+			}
+
+			response := newTestResponse(r, http.StatusOK, nil)
+			response.Body = io.NopCloser(strings.NewReader(`{}`))
+			return response, nil
+		}),
+		Cache: &ResponseCache{
+			DefaultTTL: time.Minute,
+		},
+	}
+
+	get("http://compute.example.com/v2.1")
+	get("http://compute.example.com/v2.1/servers")
+	get("http://compute.example.com/v2.1/servers/1/metadata")
+
+	request, _ := http.NewRequest("PUT", "http://compute.example.com/v2.1/servers/1", nil)
+	request.Header.Set("X-Auth-Token", "token1")
+	_, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	requests = nil
+
+	// the ancestors of the collection are kept
+	get("http://compute.example.com/v2.1")
+	// the collection, the resource and its subresources are invalidated
+	get("http://compute.example.com/v2.1/servers")
+	get("http://compute.example.com/v2.1/servers/1")
+	get("http://compute.example.com/v2.1/servers/1/metadata")
+
+	th.AssertDeepEquals(t, []string{
+		"GET /v2.1/servers",
+		"GET /v2.1/servers/1",
+		"GET /v2.1/servers/1/metadata",
+	}, requests)
+}
+
+func TestResponseCacheRecording(t *testing.T) {
+	var requests int
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			response := newTestResponse(r, http.StatusOK, http.Header{"X-Openstack-Request-Id": {"req-1"}})
+			response.Body = io.NopCloser(strings.NewReader(`{"servers": []}`))
+			return response, nil
+		}),
+		Cache: &ResponseCache{
+			Rules: []CacheRule{
+				{Pattern: regexp.MustCompile(`/servers/detail$`), TTL: time.Minute},
+			},
+		},
+		HAR: NewHARRecorder(io.Discard),
+	}
+
+	collector := &RequestIDCollector{}
+	ctx := WithRequestIDCollector(context.Background(), collector)
+
+	for range 2 {
+		request, _ := http.NewRequestWithContext(ctx, "GET", "http://compute.example.com/servers/detail", nil)
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		body, err := io.ReadAll(response.Body)
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, `{"servers": []}`, string(body))
+	}
+
+	// the cached response is recorded like the original one
+	th.AssertEquals(t, 1, requests)
+	th.AssertDeepEquals(t, []string{"req-1", "req-1"}, collector.RequestIDs())
+	th.AssertEquals(t, 2, len(rt.HAR.entries))
+}
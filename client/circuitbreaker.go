@@ -0,0 +1,352 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the default number of consecutive failures,
+	// which open a circuit.
+	DefaultFailureThreshold = 5
+
+	// DefaultOpenTimeout is the default time a circuit stays open before a
+	// trial request is allowed.
+	DefaultOpenTimeout = 30 * time.Second
+)
+
+// CircuitState represents the state of a circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed allows all requests.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests.
+	CircuitOpen
+	// CircuitHalfOpen allows a limited number of trial requests.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown (%d)", int(s))
+}
+
+// ErrCircuitOpen is returned, when a request is rejected because the circuit
+// of its host is open.
+type ErrCircuitOpen struct {
+	Host    string
+	RetryAt time.Time
+}
+
+func (e ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit for %s is open until %s", e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+// CircuitBreaker tracks the health of hosts and rejects requests to
+// unhealthy hosts. A CircuitBreaker must not be copied after first use.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures, which open a
+	// circuit. If zero, DefaultFailureThreshold is used.
+	FailureThreshold int
+
+	// OpenTimeout is the time a circuit stays open before it becomes
+	// half-open. If zero, DefaultOpenTimeout is used.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of concurrent trial requests allowed
+	// in the half-open state. If zero, 1 is used.
+	HalfOpenRequests int
+
+	// IsFailure reports whether a request attempt has failed. If nil,
+	// connection errors and 502, 503 and 504 responses are failures.
+	IsFailure func(*http.Response, error) bool
+
+	// Failover maps an endpoint URL prefix to a list of alternate endpoint
+	// URL prefixes, e.g. a public endpoint to an internal one. Idempotent
+	// requests are sent to an alternate endpoint, when the request to the
+	// original endpoint fails or its circuit is open.
+	Failover map[string][]string
+
+	// OnStateChange is called, when the circuit of a host changes its state.
+	// It is called without holding the lock of the CircuitBreaker, so the
+	// changes of concurrent requests may be reported out of order.
+	OnStateChange func(host string, from, to CircuitState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit represents the state of a single host.
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trials   int
+}
+
+// State returns the current state of a host's circuit.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[host]
+	if !ok {
+		return CircuitClosed
+	}
+
+	if c.state == CircuitOpen && !time.Now().Before(c.openedAt.Add(cb.openTimeout())) {
+		return CircuitHalfOpen
+	}
+
+	return c.state
+}
+
+func (cb *CircuitBreaker) failureThreshold() int {
+	if cb.FailureThreshold <= 0 {
+		return DefaultFailureThreshold
+	}
+	return cb.FailureThreshold
+}
+
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.OpenTimeout <= 0 {
+		return DefaultOpenTimeout
+	}
+	return cb.OpenTimeout
+}
+
+func (cb *CircuitBreaker) isFailure(response *http.Response, err error) bool {
+	if cb.IsFailure != nil {
+		return cb.IsFailure(response, err)
+	}
+
+	if err != nil || response == nil {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// stateChange represents a state transition of a circuit.
+type stateChange struct {
+	host     string
+	from, to CircuitState
+}
+
+// setState must be called with the lock held. The returned transition must
+// be passed to notify after the lock has been released.
+func (c *circuit) setState(host string, state CircuitState) *stateChange {
+	from := c.state
+	c.state = state
+	if from == state {
+		return nil
+	}
+	return &stateChange{host: host, from: from, to: state}
+}
+
+// notify calls OnStateChange. It must be called without the lock held, so
+// that the callback may use the CircuitBreaker.
+func (cb *CircuitBreaker) notify(change *stateChange) {
+	if change != nil && cb.OnStateChange != nil {
+		cb.OnStateChange(change.host, change.from, change.to)
+	}
+}
+
+// allow reports whether a request to the host may be sent.
+func (cb *CircuitBreaker) allow(host string) error {
+	var change *stateChange
+	defer func() { cb.notify(change) }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.circuits == nil {
+		cb.circuits = make(map[string]*circuit)
+	}
+
+	c, ok := cb.circuits[host]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[host] = c
+	}
+
+	if c.state == CircuitOpen {
+		retryAt := c.openedAt.Add(cb.openTimeout())
+		if time.Now().Before(retryAt) {
+			return ErrCircuitOpen{Host: host, RetryAt: retryAt}
+		}
+		c.trials = 0
+		change = c.setState(host, CircuitHalfOpen)
+	}
+
+	if c.state == CircuitHalfOpen {
+		if c.trials >= max(cb.HalfOpenRequests, 1) {
+			return ErrCircuitOpen{Host: host, RetryAt: time.Now().Add(cb.openTimeout())}
+		}
+		c.trials++
+	}
+
+	return nil
+}
+
+// record records the result of a request to the host. A nil failed value
+// means, that the result must not be taken into account, e.g. because the
+// request was cancelled by the caller.
+func (cb *CircuitBreaker) record(host string, failed *bool) {
+	var change *stateChange
+	defer func() { cb.notify(change) }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[host]
+	if !ok {
+		return
+	}
+
+	if c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+
+	if failed == nil {
+		return
+	}
+
+	if !*failed {
+		c.failures = 0
+		change = c.setState(host, CircuitClosed)
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= cb.failureThreshold() {
+		c.openedAt = time.Now()
+		change = c.setState(host, CircuitOpen)
+	}
+}
+
+// isIdempotent reports whether a request can be safely sent again.
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// failoverRequests returns copies of the request, which target the
+// alternate endpoints.
+func (cb *CircuitBreaker) failoverRequests(request *http.Request) []*http.Request {
+	if len(cb.Failover) == 0 || !isIdempotent(request) {
+		return nil
+	}
+
+	u := request.URL.String()
+
+	var prefix string
+	for k := range cb.Failover {
+		if strings.HasPrefix(u, k) && len(k) > len(prefix) {
+			prefix = k
+		}
+	}
+	if prefix == "" {
+		return nil
+	}
+
+	var requests []*http.Request
+	for _, alt := range cb.Failover[prefix] {
+		altURL, err := url.Parse(alt + strings.TrimPrefix(u, prefix))
+		if err != nil {
+			continue
+		}
+
+		r := request.Clone(request.Context())
+		r.URL = altURL
+		r.Host = ""
+		requests = append(requests, r)
+	}
+
+	return requests
+}
+
+// roundTrip sends the request using the send function unless the circuit of
+// the request host is open. Failed idempotent requests are sent to the
+// alternate endpoints. The wait function is called before a trial is taken,
+// so that a request waiting for the rate limiter does not hold a half-open
+// trial; the function it returns is called with the response.
+func (cb *CircuitBreaker) roundTrip(request *http.Request, wait func(*http.Request) (func(*http.Response), error), send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	candidates := append([]*http.Request{request}, cb.failoverRequests(request)...)
+
+	var response *http.Response
+	var err error
+	for i, r := range candidates {
+		host := r.URL.Host
+
+		finish, waitErr := wait(r)
+		if waitErr != nil {
+			// the request context is done
+			if response != nil {
+				response.Body.Close()
+			}
+			return nil, waitErr
+		}
+
+		if openErr := cb.allow(host); openErr != nil {
+			finish(nil)
+			if response == nil && err == nil {
+				err = openErr
+			}
+			continue
+		}
+
+		if i > 0 && r.GetBody != nil {
+			body, bodyErr := r.GetBody()
+			if bodyErr != nil {
+				finish(nil)
+				cb.record(host, nil)
+				continue
+			}
+			r.Body = body
+		}
+
+		// discard the previous failed response
+		if response != nil {
+			response.Body.Close()
+		}
+
+		response, err = send(r)
+		finish(response)
+
+		if r.Context().Err() != nil {
+			cb.record(host, nil)
+			return response, err
+		}
+
+		failed := cb.isFailure(response, err)
+		cb.record(host, &failed)
+		if !failed {
+			return response, err
+		}
+	}
+
+	return response, err
+}
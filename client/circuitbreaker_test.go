@@ -0,0 +1,147 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	var calls atomic.Int32
+	var code atomic.Int32
+	code.Store(http.StatusServiceUnavailable)
+
+	cb := &CircuitBreaker{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			return newTestResponse(r, int(code.Load()), nil), nil
+		}),
+		CircuitBreaker: cb,
+	}
+
+	for range 2 {
+		request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, http.StatusServiceUnavailable, response.StatusCode)
+	}
+	th.AssertEquals(t, CircuitOpen, cb.State("compute.example.com"))
+
+	request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	_, err := rt.RoundTrip(request)
+	var openErr ErrCircuitOpen
+	if !errors.As(err, &openErr) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	th.AssertEquals(t, "compute.example.com", openErr.Host)
+	th.AssertEquals(t, int32(2), calls.Load())
+
+	time.Sleep(25 * time.Millisecond)
+	th.AssertEquals(t, CircuitHalfOpen, cb.State("compute.example.com"))
+
+	code.Store(http.StatusOK)
+	request, _ = http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	response, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, response.StatusCode)
+	th.AssertEquals(t, CircuitClosed, cb.State("compute.example.com"))
+}
+
+func TestCircuitBreakerFailover(t *testing.T) {
+	var hosts []string
+	cb := &CircuitBreaker{
+		Failover: map[string][]string{
+			"https://public.example.com:8774/v2.1": {"http://internal.example.com:8774/v2.1"},
+		},
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			hosts = append(hosts, r.URL.Host)
+			if r.URL.Host == "public.example.com:8774" {
+				return nil, errors.New("connection timed out")
+			}
+			th.AssertEquals(t, "/v2.1/servers/detail", r.URL.Path)
+			return newTestResponse(r, http.StatusOK, nil), nil
+		}),
+		CircuitBreaker: cb,
+	}
+
+	request, _ := http.NewRequest("GET", "https://public.example.com:8774/v2.1/servers/detail", nil)
+	response, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, response.StatusCode)
+	th.AssertDeepEquals(t, []string{"public.example.com:8774", "internal.example.com:8774"}, hosts)
+
+	// non-idempotent requests are not failed over
+	hosts = nil
+	request, _ = http.NewRequest("POST", "https://public.example.com:8774/v2.1/servers", nil)
+	_, err = rt.RoundTrip(request)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertDeepEquals(t, []string{"public.example.com:8774"}, hosts)
+}
+
+func TestCircuitBreakerOnStateChange(t *testing.T) {
+	var changes []string
+	cb := &CircuitBreaker{
+		FailureThreshold: 1,
+	}
+	// the callback may use the CircuitBreaker
+	cb.OnStateChange = func(host string, from, to CircuitState) {
+		changes = append(changes, from.String()+" -> "+cb.State(host).String())
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return newTestResponse(r, http.StatusBadGateway, nil), nil
+		}),
+		CircuitBreaker: cb,
+	}
+
+	request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	_, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, []string{"closed -> open"}, changes)
+}
+
+func TestCircuitBreakerRateLimit(t *testing.T) {
+	cb := &CircuitBreaker{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Millisecond,
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return newTestResponse(r, http.StatusServiceUnavailable, nil), nil
+		}),
+		CircuitBreaker: cb,
+		RateLimiter: &RateLimiter{
+			Default: RateLimit{Rate: 10, Burst: 1},
+		},
+	}
+
+	request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	_, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+		_, _ = rt.RoundTrip(request)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the request waiting for the rate limiter does not hold the trial
+	th.AssertNoErr(t, cb.allow("compute.example.com"))
+	cb.record("compute.example.com", nil)
+	<-done
+}
@@ -1,9 +1,8 @@
 package client
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -47,11 +46,42 @@ type RoundTripper struct {
 	maskHeaders *map[string]struct{}
 	// A custom function to format and mask JSON requests and responses
 	FormatJSON func([]byte) (string, error)
+	// A custom set of rules to mask JSON requests and responses. It is used
+	// when FormatJSON is nil. If Redactor is nil, the rules returned by
+	// DefaultRedactionRules are used.
+	Redactor *Redactor
 	// How many times HTTP connection should be retried until giving up
 	MaxRetries int
 	// If Logger is not nil, then RoundTrip method will debug the JSON
 	// requests and responses
 	Logger Logger
+	// If RateLimiter is not nil, then requests are delayed according to its
+	// rate and concurrency limits
+	RateLimiter *RateLimiter
+	// If Microversions is not nil, then the microversions negotiated by it
+	// are set in requests, which have no microversion set
+	Microversions *MicroversionNegotiator
+	// If Cache is not nil, then the responses of GET requests are cached.
+	// Cached responses are recorded by HAR, Audit and request ID
+	// collectors like responses of the service
+	Cache *ResponseCache
+	// If HAR is not nil, then requests and responses are recorded in it
+	HAR *HARRecorder
+	// If CircuitBreaker is not nil, then requests to unhealthy hosts fail
+	// fast with ErrCircuitOpen
+	CircuitBreaker *CircuitBreaker
+	// If TokenRefresher is not nil, then expiring tokens are refreshed
+	// and requests rejected with 401 Unauthorized are retried once
+	TokenRefresher *TokenRefresher
+	// If Compression is not nil, then responses are requested compressed
+	// and decompressed transparently, and request bodies may be compressed
+	Compression *Compression
+	// If Audit is not nil, then requests modifying resources are recorded
+	// in it regardless of the Logger
+	Audit *AuditLog
+	// Maximum size of a request or response body to be logged. Larger
+	// bodies are logged truncated. If zero, DefaultMaxLogBodySize is used
+	MaxLogBodySize int64
 }
 
 // List of headers that contain sensitive data.
@@ -112,16 +142,8 @@ func (rt *RoundTripper) hideSensitiveHeadersData(headers http.Header) []string {
 	result := make([]string, len(headers))
 	headerIdx := 0
 
-	// this is concurrency safe
-	v := rt.maskHeaders
-	if v == nil {
-		v = &defaultSensitiveHeaders
-	}
-	maskHeaders := *v
-
 	for header, data := range headers {
-		v := strings.ToLower(header)
-		if _, ok := maskHeaders[v]; ok {
+		if rt.isSensitiveHeader(header) {
 			result[headerIdx] = fmt.Sprintf("%s: %s", header, "***")
 		} else {
 			result[headerIdx] = fmt.Sprintf("%s: %s", header, strings.Join(data, " "))
@@ -132,6 +154,19 @@ func (rt *RoundTripper) hideSensitiveHeadersData(headers http.Header) []string {
 	return result
 }
 
+// isSensitiveHeader reports whether the header must be masked.
+func (rt *RoundTripper) isSensitiveHeader(header string) bool {
+	// this is concurrency safe
+	v := rt.maskHeaders
+	if v == nil {
+		v = &defaultSensitiveHeaders
+	}
+	maskHeaders := *v
+
+	_, ok := maskHeaders[strings.ToLower(header)]
+	return ok
+}
+
 // formatHeaders converts standard http.Header type to a string with separated headers.
 // It will hide data of sensitive headers.
 func (rt *RoundTripper) formatHeaders(headers http.Header, separator string) string {
@@ -161,9 +196,42 @@ func (rt *RoundTripper) RoundTrip(request *http.Request) (*http.Response, error)
 		}
 	}
 
+	// this is concurrency safe
+	if m := rt.Microversions; m != nil {
+		m.setHeaders(request)
+	}
+
+	// this is concurrency safe
+	compression := rt.Compression
+	var decompress bool
+	if compression != nil {
+		decompress = compression.acceptEncoding(request)
+	}
+
+	setRequestID(request)
+
+	// this is concurrency safe
+	refresher := rt.TokenRefresher
+	if refresher != nil {
+		if err := refresher.prepare(request); err != nil {
+			return nil, err
+		}
+	}
+
+	// this is concurrency safe
+	cache := rt.Cache
+	var cached *cacheEntry
+	var fromCache *http.Response
+	if cache != nil {
+		cached, fromCache = cache.prepare(request)
+		if fromCache != nil && rt.Logger != nil {
+			rt.log().Printf("OpenStack Response served from cache: %s %s", request.Method, request.URL)
+		}
+	}
+
 	var err error
 
-	if rt.Logger != nil {
+	if rt.Logger != nil && fromCache == nil {
 		rt.log().Printf("OpenStack Request URL: %s %s", request.Method, request.URL)
 		rt.log().Printf("OpenStack Request Headers:\n%s", rt.formatHeaders(request.Header, "\n"))
 
@@ -177,14 +245,102 @@ func (rt *RoundTripper) RoundTrip(request *http.Request) (*http.Response, error)
 
 	// this is concurrency safe
 	ort := rt.Rt
-	if ort == nil {
+	if ort == nil && fromCache == nil {
 		return nil, fmt.Errorf("Rt RoundTripper is nil, aborting") //nolint
 	}
-	response, err := ort.RoundTrip(request)
+
+	// this is concurrency safe
+	audit := rt.Audit
+	var auditRecord *AuditRecord
+	if audit != nil {
+		auditRecord, err = rt.auditStart(audit, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// this is concurrency safe
+	var harFinish func(*http.Response, error) (*http.Response, error)
+	if har := rt.HAR; har != nil {
+		request, harFinish, err = rt.harStart(har, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response := fromCache
+	if response == nil {
+		if compression != nil {
+			if err = compression.compressRequest(request); err != nil {
+				return nil, err
+			}
+		}
+
+		response, err = rt.retryRoundTrip(ort, request)
+		if refresher != nil && response != nil {
+			retry, refreshErr := refresher.retryUnauthorized(request, response)
+			if refreshErr != nil {
+				// the 401 response is returned as is
+				if rt.Logger != nil {
+					rt.log().Printf("OpenStack token refresh failed: %s", refreshErr)
+				}
+			} else if retry != nil {
+				if rt.Logger != nil {
+					rt.log().Printf("OpenStack token has been refreshed, retrying the request")
+				}
+				request = retry
+				response, err = rt.retryRoundTrip(ort, request)
+			}
+		}
+		if decompress && response != nil {
+			decompressResponse(response)
+		}
+	}
+	if harFinish != nil {
+		response, err = harFinish(response, err)
+	}
+	if audit != nil {
+		response, err = rt.auditFinish(audit, auditRecord, request, response, err)
+	}
+	if response == nil {
+		return nil, err
+	}
+
+	collectRequestID(request, response)
+
+	if fromCache != nil {
+		return response, err
+	}
+
+	if cache != nil {
+		response, err = cache.update(request, cached, response)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rt.Logger != nil {
+		rt.log().Printf("OpenStack Response Code: %d", response.StatusCode)
+		rt.log().Printf("OpenStack Response Headers:\n%s", rt.formatHeaders(response.Header, "\n"))
+
+		response.Body, err = rt.logResponse(response.Body, response.Header.Get("Content-Type"))
+	}
+
+	return response, err
+}
+
+// retryRoundTrip performs the HTTP request and retries it up to MaxRetries
+// times, when it doesn't return a response.
+func (rt *RoundTripper) retryRoundTrip(ort http.RoundTripper, request *http.Request) (*http.Response, error) {
+	response, err := rt.doRoundTrip(ort, request)
 
 	// If the first request didn't return a response, retry up to `max_retries`.
 	retry := 1
 	for response == nil {
+		if request.Context().Err() != nil || errors.As(err, &ErrCircuitOpen{}) {
+			return nil, err
+		}
+
 		if retry > rt.MaxRetries {
 			if rt.Logger != nil {
 				rt.log().Printf("OpenStack connection error, retries exhausted. Aborting")
@@ -196,82 +352,86 @@ func (rt *RoundTripper) RoundTrip(request *http.Request) (*http.Response, error)
 		if rt.Logger != nil {
 			rt.log().Printf("OpenStack connection error, retry number %d: %s", retry, err)
 		}
-		response, err = ort.RoundTrip(request)
+		response, err = rt.doRoundTrip(ort, request)
 		retry += 1
 	}
 
-	if rt.Logger != nil {
-		rt.log().Printf("OpenStack Response Code: %d", response.StatusCode)
-		rt.log().Printf("OpenStack Response Headers:\n%s", rt.formatHeaders(response.Header, "\n"))
+	return response, err
+}
 
-		response.Body, err = rt.logResponse(response.Body, response.Header.Get("Content-Type"))
+// doRoundTrip performs a single attempt of the HTTP request using the
+// original RoundTripper.
+func (rt *RoundTripper) doRoundTrip(ort http.RoundTripper, request *http.Request) (*http.Response, error) {
+	// this is concurrency safe
+	cb := rt.CircuitBreaker
+	if cb != nil {
+		return cb.roundTrip(request, rt.waitRateLimit, ort.RoundTrip)
 	}
 
+	finish, err := rt.waitRateLimit(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ort.RoundTrip(request)
+	finish(response)
+
 	return response, err
 }
 
-// logRequest will log the HTTP Request details.
-// If the body is JSON, it will attempt to be pretty-formatted.
-func (rt *RoundTripper) logRequest(original io.ReadCloser, contentType string) (io.ReadCloser, error) {
-	// Handle request contentType
-	if strings.HasPrefix(contentType, "application/json") || (strings.HasPrefix(contentType, "application/") && strings.HasSuffix(contentType, "-json-patch")) {
-		var bs bytes.Buffer
-		defer original.Close()
+// waitRateLimit blocks until the rate limiter allows the request to be
+// sent. The returned function must be called with the response, or nil, if
+// the request has not been sent.
+func (rt *RoundTripper) waitRateLimit(request *http.Request) (func(*http.Response), error) {
+	// this is concurrency safe
+	limiter := rt.RateLimiter
+	if limiter == nil {
+		return func(*http.Response) {}, nil
+	}
 
-		_, err := io.Copy(&bs, original)
-		if err != nil {
-			return nil, err
-		}
+	release, err := limiter.Wait(request)
+	if err != nil {
+		return nil, err
+	}
 
-		debugInfo, err := rt.formatJSON()(bs.Bytes())
-		if err != nil {
-			rt.log().Printf("%s", err)
-		}
-		rt.log().Printf("OpenStack Request Body: %s", debugInfo)
+	return func(response *http.Response) {
+		limiter.Observe(request, response)
 
-		return io.NopCloser(strings.NewReader(bs.String())), nil
-	}
+		// the request is in flight, until its response body is consumed
+		releaseBody(response, release)
+	}, nil
+}
 
-	rt.log().Printf("Not logging because OpenStack request body isn't JSON")
-	return original, nil
+// logRequest will log the HTTP Request details.
+// If the body is JSON, it will attempt to be pretty-formatted.
+func (rt *RoundTripper) logRequest(original io.ReadCloser, contentType string) (io.ReadCloser, error) {
+	return rt.logBody("Request", original, contentType)
 }
 
 // logResponse will log the HTTP Response details.
 // If the body is JSON, it will attempt to be pretty-formatted.
 func (rt *RoundTripper) logResponse(original io.ReadCloser, contentType string) (io.ReadCloser, error) {
-	if strings.HasPrefix(contentType, "application/json") {
-		var bs bytes.Buffer
-		defer original.Close()
-
-		_, err := io.Copy(&bs, original)
-		if err != nil {
-			return nil, err
-		}
-
-		debugInfo, err := rt.formatJSON()(bs.Bytes())
-		if err != nil {
-			rt.log().Printf("%s", err)
-		}
-		if debugInfo != "" {
-			rt.log().Printf("OpenStack Response Body: %s", debugInfo)
-		}
-
-		return io.NopCloser(strings.NewReader(bs.String())), nil
-	}
-
-	rt.log().Printf("Not logging because OpenStack response body isn't JSON")
-	return original, nil
+	return rt.logBody("Response", original, contentType)
 }
 
 func (rt *RoundTripper) formatJSON() func([]byte) (string, error) {
 	// this is concurrency safe
 	f := rt.FormatJSON
 	if f == nil {
-		return FormatJSON
+		return rt.redactor().FormatJSON
 	}
 	return f
 }
 
+func (rt *RoundTripper) redactor() *Redactor {
+	// this is concurrency safe
+	r := rt.Redactor
+	if r == nil {
+		return defaultRedactor
+	}
+	return r
+}
+
 func (rt *RoundTripper) log() Logger {
 	// this is concurrency safe
 	l := rt.Logger
@@ -283,99 +443,16 @@ func (rt *RoundTripper) log() Logger {
 }
 
 // FormatJSON is a default function to pretty-format a JSON body.
-// It will also mask known fields which contain sensitive information.
+// It will also mask known fields which contain sensitive information, see
+// DefaultRedactionRules.
 func FormatJSON(raw []byte) (string, error) {
-	var rawData any
-
-	err := json.Unmarshal(raw, &rawData)
-	if err != nil {
-		return string(raw), fmt.Errorf("unable to parse OpenStack JSON: %s", err)
-	}
-
-	data, ok := rawData.(map[string]any)
-	if !ok {
-		pretty, err := json.MarshalIndent(rawData, "", "  ")
-		if err != nil {
-			return string(raw), fmt.Errorf("unable to re-marshal OpenStack JSON: %s", err)
-		}
-
-		return string(pretty), nil
-	}
-
-	// Mask known password fields
-	if v, ok := data["auth"].(map[string]any); ok {
-		// v2 auth methods
-		if v, ok := v["passwordCredentials"].(map[string]any); ok {
-			v["password"] = "***"
-		}
-		if v, ok := v["token"].(map[string]any); ok {
-			v["id"] = "***"
-		}
-		// v3 auth methods
-		if v, ok := v["identity"].(map[string]any); ok {
-			if v, ok := v["password"].(map[string]any); ok {
-				if v, ok := v["user"].(map[string]any); ok {
-					v["password"] = "***"
-				}
-			}
-			if v, ok := v["application_credential"].(map[string]any); ok {
-				v["secret"] = "***"
-			}
-			if v, ok := v["token"].(map[string]any); ok {
-				v["id"] = "***"
-			}
-		}
-	}
-
-	// Mask EC2 access id and body hash
-	if v, ok := data["credentials"].(map[string]any); ok {
-		var access string
-		if s, ok := v["access"]; ok {
-			access, _ = s.(string)
-			v["access"] = "***"
-		}
-		if _, ok := v["body_hash"]; ok {
-			v["body_hash"] = "***"
-		}
-		if v, ok := v["headers"].(map[string]any); ok {
-			if _, ok := v["Authorization"]; ok {
-				if s, ok := v["Authorization"].(string); ok {
-					v["Authorization"] = strings.ReplaceAll(s, access, "***")
-				}
-			}
-		}
-	}
-
-	// Ignore the huge catalog output
-	if v, ok := data["token"].(map[string]any); ok {
-		if _, ok := v["catalog"]; ok {
-			v["catalog"] = "***"
-		}
-	}
-
-	pretty, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return string(raw), fmt.Errorf("unable to re-marshal OpenStack JSON: %s", err)
-	}
-
-	return string(pretty), nil
+	return defaultRedactor.FormatJSON(raw)
 }
 
 func RetryBackoffFunc(logger Logger) gophercloud.RetryBackoffFunc {
 	return func(ctx context.Context, respErr *gophercloud.ErrUnexpectedResponseCode, e error, retries uint) error {
-		retryAfter := respErr.ResponseHeader.Get("Retry-After")
-		if retryAfter == "" {
-			return e
-		}
-
-		var sleep time.Duration
-
-		// Parse delay seconds or HTTP date
-		if v, err := strconv.ParseUint(retryAfter, 10, 32); err == nil {
-			sleep = time.Duration(v) * time.Second
-		} else if v, err := time.Parse(http.TimeFormat, retryAfter); err == nil {
-			sleep = time.Until(v)
-		} else {
+		sleep, ok := parseRetryAfter(respErr.ResponseHeader.Get("Retry-After"))
+		if !ok {
 			return e
 		}
 
@@ -399,3 +476,21 @@ func RetryBackoffFunc(logger Logger) gophercloud.RetryBackoffFunc {
 		return nil
 	}
 }
+
+// parseRetryAfter parses the Retry-After header value, which contains either
+// delay seconds or an HTTP date.
+func parseRetryAfter(retryAfter string) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+
+	if v, err := strconv.ParseUint(retryAfter, 10, 32); err == nil {
+		return time.Duration(v) * time.Second, true
+	}
+
+	if v, err := time.Parse(http.TimeFormat, retryAfter); err == nil {
+		return time.Until(v), true
+	}
+
+	return 0, false
+}
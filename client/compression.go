@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultCompressionMinSize is the default minimum size of a request body to
+// be compressed.
+const DefaultCompressionMinSize = 1024
+
+// DefaultCompressionMaxSize is the default maximum size of a request body to
+// be compressed.
+const DefaultCompressionMaxSize = 8 * 1024 * 1024
+
+// Compression configures the compression of requests and responses.
+// Responses are requested with the "Accept-Encoding: gzip, deflate" header
+// and decompressed transparently, regardless of the compression settings of
+// the original RoundTripper. Requests, which set the Accept-Encoding or the
+// Range header themselves, receive the response as is.
+type Compression struct {
+	// RequestPattern is a regular expression the URL of a request must
+	// match to have its body compressed with gzip. Only the URLs of the
+	// services known to accept compressed request bodies should match. If
+	// nil, request bodies are not compressed.
+	RequestPattern *regexp.Regexp
+
+	// MinSize is the minimum size of a request body to be compressed. If
+	// zero, DefaultCompressionMinSize is used.
+	MinSize int64
+
+	// MaxSize is the maximum size of a request body to be compressed, since
+	// the compressed body is buffered in memory. If zero,
+	// DefaultCompressionMaxSize is used.
+	MaxSize int64
+
+	// Level is the gzip compression level of request bodies. If zero,
+	// gzip.DefaultCompression is used.
+	Level int
+}
+
+func (c *Compression) minSize() int64 {
+	if c.MinSize > 0 {
+		return c.MinSize
+	}
+	return DefaultCompressionMinSize
+}
+
+func (c *Compression) maxSize() int64 {
+	if c.MaxSize > 0 {
+		return c.MaxSize
+	}
+	return DefaultCompressionMaxSize
+}
+
+func (c *Compression) level() int {
+	if c.Level != 0 {
+		return c.Level
+	}
+	return gzip.DefaultCompression
+}
+
+// acceptEncoding sets the Accept-Encoding header of a request. It returns
+// true, when the response must be decompressed.
+func (c *Compression) acceptEncoding(request *http.Request) bool {
+	if request.Method == http.MethodHead ||
+		request.Header.Get("Accept-Encoding") != "" ||
+		request.Header.Get("Range") != "" {
+		return false
+	}
+
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	return true
+}
+
+// compressRequest compresses the body of a request with a known length
+// between MinSize and MaxSize, which matches the RequestPattern. Binary
+// bodies are not compressed, since they are mostly compressed already and
+// may be arbitrarily large.
+func (c *Compression) compressRequest(request *http.Request) error {
+	if c.RequestPattern == nil ||
+		request.Body == nil || request.Body == http.NoBody ||
+		request.ContentLength < c.minSize() ||
+		request.ContentLength > c.maxSize() ||
+		request.Header.Get("Content-Encoding") != "" ||
+		getBodyKind(request.Header.Get("Content-Type")) == bodyBinary ||
+		!c.RequestPattern.MatchString(request.URL.String()) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, c.level())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(zw, request.Body); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	request.Body.Close()
+
+	data := buf.Bytes()
+	request.Body = io.NopCloser(bytes.NewReader(data))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	request.ContentLength = int64(len(data))
+	request.Header.Set("Content-Encoding", "gzip")
+
+	return nil
+}
+
+// decompressResponse replaces the body of a gzip or deflate encoded
+// response with its decompressed content.
+func decompressResponse(response *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	if encoding != "gzip" && encoding != "deflate" {
+		return
+	}
+
+	if response.Body == nil || response.Body == http.NoBody ||
+		response.StatusCode == http.StatusNoContent ||
+		response.StatusCode == http.StatusNotModified {
+		return
+	}
+
+	response.Body = &decompressReader{
+		rc:       response.Body,
+		encoding: encoding,
+	}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+}
+
+// decompressReader decompresses a body. The decompressor is created on the
+// first read, since it reads the stream header.
+type decompressReader struct {
+	rc       io.ReadCloser
+	encoding string
+	zr       io.Reader
+	err      error
+}
+
+func (r *decompressReader) Read(p []byte) (int, error) {
+	if r.zr == nil && r.err == nil {
+		r.zr, r.err = newDecompressor(r.rc, r.encoding)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.zr.Read(p)
+}
+
+func (r *decompressReader) Close() error {
+	return r.rc.Close()
+}
+
+// newDecompressor returns a reader decompressing the gzip or deflate
+// stream. Deflate is expected to be zlib wrapped, as defined by RFC 9110,
+// but raw deflate streams sent by some servers are accepted as well.
+func newDecompressor(r io.Reader, encoding string) (io.Reader, error) {
+	if encoding == "gzip" {
+		return gzip.NewReader(r)
+	}
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
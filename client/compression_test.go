@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestCompression(t *testing.T) {
+	servers := `{"servers": [` + strings.Repeat(`{"id": "1"},`, 100) + `{"id": "2"}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		th.AssertEquals(t, "gzip, deflate", r.Header.Get("Accept-Encoding"))
+
+		if r.Method == "PUT" {
+			th.AssertEquals(t, "", r.Header.Get("Content-Encoding"))
+			body, err := io.ReadAll(r.Body)
+			th.AssertNoErr(t, err)
+			th.AssertEquals(t, servers, string(body))
+			return
+		}
+
+		if r.Method == "POST" {
+			th.AssertEquals(t, "gzip", r.Header.Get("Content-Encoding"))
+			zr, err := gzip.NewReader(r.Body)
+			th.AssertNoErr(t, err)
+			body, err := io.ReadAll(zr)
+			th.AssertNoErr(t, err)
+			th.AssertEquals(t, servers, string(body))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "deflate")
+			zw := zlib.NewWriter(w)
+			_, _ = io.WriteString(zw, `{"server": {"id": "1"}}`)
+			_ = zw.Close()
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		_, _ = io.WriteString(zw, servers)
+		_ = zw.Close()
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	rt := &RoundTripper{
+		Rt:     &http.Transport{DisableCompression: true},
+		Logger: logger,
+		Compression: &Compression{
+			RequestPattern: regexp.MustCompile(`/servers$`),
+		},
+	}
+
+	request, _ := http.NewRequest("GET", server.URL+"/servers/detail", nil)
+	response, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	body, err := io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, servers, string(body))
+	th.AssertEquals(t, "", response.Header.Get("Content-Encoding"))
+	th.AssertEquals(t, true, strings.Contains(logger.String(), `"id": "2"`))
+
+	request, _ = http.NewRequest("POST", server.URL+"/servers", bytes.NewReader([]byte(servers)))
+	request.Header.Set("Content-Type", "application/json")
+	response, err = rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	body, err = io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, `{"server": {"id": "1"}}`, string(body))
+
+	// bodies larger than MaxSize are sent as is
+	rt.Compression.MaxSize = int64(len(servers) - 1)
+	request, _ = http.NewRequest("PUT", server.URL+"/servers", bytes.NewReader([]byte(servers)))
+	request.Header.Set("Content-Type", "application/json")
+	response, err = rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertNoErr(t, response.Body.Close())
+}
@@ -142,5 +142,146 @@ Example usage with additinal headers:
 			Region: os.Getenv("OS_REGION_NAME"),
 		})
 	}
+
+Example usage with custom redaction rules:
+
+	rules := append(client.DefaultRedactionRules(),
+		client.RedactionRule{Path: "server.metadata.db_password"},
+		client.RedactionRule{Key: "*_token"},
+	)
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt:       &http.Transport{},
+			Logger:   &client.DefaultLogger{},
+			Redactor: client.NewRedactor(rules...),
+		},
+	}
+
+Example usage with rate and concurrency limits, which are combined with the
+Retry-After handling of RetryBackoffFunc:
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt: &http.Transport{},
+			RateLimiter: &client.RateLimiter{
+				Default: client.RateLimit{Rate: 10, Burst: 20, MaxInFlight: 8},
+				Methods: map[string]client.RateLimit{
+					"POST": {Rate: 1, MaxInFlight: 2},
+				},
+			},
+		},
+	}
+	provider.RetryBackoffFunc = client.RetryBackoffFunc(nil)
+
+Example usage with a global request ID, which correlates the requests of a
+single operation across services:
+
+	collector := &client.RequestIDCollector{}
+	ctx := client.WithRequestID(context.Background(), client.NewRequestID())
+	ctx = client.WithRequestIDCollector(ctx, collector)
+
+	err := helpers.ProjectPurgeAll(ctx, projectID, purgeOpts)
+
+	for _, e := range collector.Entries() {
+		log.Printf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.RequestID)
+	}
+
+Example usage with a HAR export of the HTTP traffic:
+
+	f, err := os.Create("session.har")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	har := client.NewHARRecorder(f)
+	defer har.Close()
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt:  &http.Transport{},
+			HAR: har,
+		},
+	}
+
+Example usage with a transparent token refresh, when AllowReauth is
+disabled, e.g. with application credentials:
+
+	ao, err := clientconfig.AuthOptions(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := openstack.NewClient(ao.IdentityEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &client.RoundTripper{
+		Rt: &http.Transport{},
+	}
+	provider.HTTPClient = http.Client{
+		Transport: rt,
+	}
+
+	err = openstack.Authenticate(ctx, provider, *ao)
+	if err != nil {
+		return nil, err
+	}
+
+	rt.TokenRefresher = client.NewProviderTokenRefresher(provider, *ao)
+
+Example usage with compressed responses and compressed Gnocchi batch
+requests:
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt: &http.Transport{},
+			Compression: &client.Compression{
+				RequestPattern: regexp.MustCompile(`/v1/batch/`),
+			},
+		},
+	}
+
+Example usage with an audit log of the requests modifying resources, which
+is written regardless of the debug logging:
+
+	sink, err := client.OpenJSONLinesAuditFile("/var/log/openstack-audit.jsonl")
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt:    &http.Transport{},
+			Audit: &client.AuditLog{Sink: sink},
+		},
+	}
+
+Example usage with fault injection in tests:
+
+	provider.HTTPClient = http.Client{
+		Transport: &client.RoundTripper{
+			Rt: &client.FaultInjector{
+				Rt:   &http.Transport{},
+				Seed: 1,
+				Rules: []client.FaultRule{
+					{
+						Methods:     []string{"POST"},
+						URLPattern:  regexp.MustCompile(`/servers$`),
+						Probability: 0.2,
+						Fault:       client.Fault{StatusCode: http.StatusConflict},
+					},
+					{
+						Probability: 0.05,
+						Fault:       client.Fault{Latency: 2 * time.Second, ConnectionReset: true},
+					},
+				},
+			},
+			MaxRetries: 3,
+		},
+	}
 */
 package client
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// expiredTokenBody is a body of a Keystone 401 response.
+const expiredTokenBody = `{"error": {"code": 401, "title": "Unauthorized", "message": "The request you have made requires authentication."}}`
+
+// Fault describes a fault to be injected into a request.
+type Fault struct {
+	// Latency delays the request by the specified duration.
+	Latency time.Duration
+
+	// ConnectionReset fails the request with a connection reset error
+	// without sending it.
+	ConnectionReset bool
+
+	// StatusCode responds with the specified status code without sending
+	// the request.
+	StatusCode int
+
+	// Header contains headers of the injected response, e.g. Retry-After.
+	Header http.Header
+
+	// Body is a body of the injected response.
+	Body string
+
+	// ExpiredToken responds with a 401 Keystone error without sending the
+	// request.
+	ExpiredToken bool
+
+	// TruncateBody sends the request and truncates the response body after
+	// TruncateAfter bytes with an io.ErrUnexpectedEOF error. Bodies, which
+	// are not longer than TruncateAfter bytes, are returned unchanged.
+	TruncateBody bool
+
+	// TruncateAfter is the number of bytes of the response body to be
+	// returned, when TruncateBody is set.
+	TruncateAfter int64
+}
+
+// FaultRule describes which requests a fault is injected into.
+type FaultRule struct {
+	// Methods is a list of HTTP methods the rule applies to. If empty, the
+	// rule applies to all methods.
+	Methods []string
+
+	// URLPattern is a regular expression the request URL must match. If
+	// nil, the rule applies to all URLs.
+	URLPattern *regexp.Regexp
+
+	// Probability is the probability of the fault between 0 and 1. If zero,
+	// the fault is always injected.
+	Probability float64
+
+	// MaxCount limits the number of injected faults. Zero means no limit.
+	MaxCount int
+
+	// Fault is the fault to be injected.
+	Fault Fault
+}
+
+// FaultInjector satisfies the http.RoundTripper interface and injects faults
+// into requests according to a list of rules. It is intended for testing
+// and can be used as the RoundTripper.Rt. A FaultInjector must not be copied
+// after first use.
+type FaultInjector struct {
+	// Default http.RoundTripper
+	Rt http.RoundTripper
+
+	// Rules is a list of fault rules. The first matching rule applies.
+	Rules []FaultRule
+
+	// Seed initializes the random number generator, which makes the fault
+	// injection reproducible.
+	Seed uint64
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	counts []int
+}
+
+// pick returns the fault of the first matching rule.
+func (fi *FaultInjector) pick(request *http.Request) *Fault {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	if fi.rng == nil {
+		fi.rng = rand.New(rand.NewPCG(fi.Seed, fi.Seed))
+	}
+	if len(fi.counts) != len(fi.Rules) {
+		fi.counts = make([]int, len(fi.Rules))
+	}
+
+	for i := range fi.Rules {
+		rule := &fi.Rules[i]
+
+		if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool {
+			return strings.EqualFold(m, request.Method)
+		}) {
+			continue
+		}
+
+		if rule.URLPattern != nil && !rule.URLPattern.MatchString(request.URL.String()) {
+			continue
+		}
+
+		if rule.MaxCount > 0 && fi.counts[i] >= rule.MaxCount {
+			continue
+		}
+
+		if rule.Probability > 0 && fi.rng.Float64() >= rule.Probability {
+			continue
+		}
+
+		fi.counts[i]++
+
+		return &rule.Fault
+	}
+
+	return nil
+}
+
+// RoundTrip injects a fault into the request or sends it using the default
+// RoundTripper.
+func (fi *FaultInjector) RoundTrip(request *http.Request) (*http.Response, error) {
+	ort := fi.Rt
+	if ort == nil {
+		return nil, fmt.Errorf("Rt RoundTripper is nil, aborting") //nolint
+	}
+
+	fault := fi.pick(request)
+	if fault == nil {
+		return ort.RoundTrip(request)
+	}
+
+	if fault.Latency > 0 {
+		if err := waitContext(request.Context(), fault.Latency); err != nil {
+			closeRequestBody(request)
+			return nil, err
+		}
+	}
+
+	if fault.ConnectionReset {
+		closeRequestBody(request)
+		return nil, &net.OpError{
+			Op:   "read",
+			Net:  "tcp",
+			Addr: fakeAddr(request.URL.Host),
+			Err:  os.NewSyscallError("read", syscall.ECONNRESET),
+		}
+	}
+
+	if fault.ExpiredToken || fault.StatusCode != 0 {
+		closeRequestBody(request)
+		return fault.response(request), nil
+	}
+
+	response, err := ort.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+
+	if fault.TruncateBody {
+		response.Body = &readCloser{
+			Reader: &truncatedReader{r: response.Body, remaining: fault.TruncateAfter},
+			Closer: response.Body,
+		}
+		response.ContentLength = -1
+	}
+
+	return response, nil
+}
+
+// response returns an injected response.
+func (f *Fault) response(request *http.Request) *http.Response {
+	code := f.StatusCode
+	body := f.Body
+	header := make(http.Header, len(f.Header))
+	for k, v := range f.Header {
+		header[k] = slices.Clone(v)
+	}
+
+	if f.ExpiredToken {
+		code = http.StatusUnauthorized
+		if body == "" {
+			body = expiredTokenBody
+		}
+		if header.Get("WWW-Authenticate") == "" {
+			header.Set("WWW-Authenticate", fmt.Sprintf("Keystone uri=%q", request.URL.Scheme+"://"+request.URL.Host))
+		}
+	}
+
+	if body != "" && header.Get("Content-Type") == "" {
+		if strings.HasPrefix(body, "{") {
+			header.Set("Content-Type", "application/json")
+		} else {
+			header.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+func closeRequestBody(request *http.Request) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+}
+
+// truncatedReader returns io.ErrUnexpectedEOF after the remaining bytes,
+// when the body continues beyond them.
+type truncatedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (t *truncatedReader) Read(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		var b [1]byte
+		n, err := io.ReadAtLeast(t.r, b[:], 1)
+		if n > 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > t.remaining {
+		p = p[:t.remaining]
+	}
+
+	n, err := t.r.Read(p)
+	t.remaining -= int64(n)
+	return n, err
+}
+
+// fakeAddr satisfies the net.Addr interface.
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestFaultInjector(t *testing.T) {
+	var sent int
+	fi := &FaultInjector{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			sent++
+			response := newTestResponse(r, http.StatusOK, nil)
+			response.Body = io.NopCloser(strings.NewReader("0123456789"))
+			return response, nil
+		}),
+		Rules: []FaultRule{
+			{
+				Methods:    []string{"PUT"},
+				URLPattern: regexp.MustCompile(`/v1/AUTH_[^/]+/`),
+				MaxCount:   1,
+				Fault: Fault{
+					StatusCode: http.StatusRequestEntityTooLarge,
+					Header:     http.Header{"Retry-After": {"1"}},
+				},
+			},
+			{
+				Methods: []string{"DELETE"},
+				Fault:   Fault{ConnectionReset: true},
+			},
+			{
+				URLPattern: regexp.MustCompile(`/servers$`),
+				Fault:      Fault{ExpiredToken: true},
+			},
+			{
+				URLPattern: regexp.MustCompile(`/images$`),
+				Fault:      Fault{TruncateBody: true, TruncateAfter: 4},
+			},
+		},
+	}
+
+	request, _ := http.NewRequest("PUT", "http://swift.example.com/v1/AUTH_test/container/object", strings.NewReader("data"))
+	response, err := fi.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+	th.AssertEquals(t, "1", response.Header.Get("Retry-After"))
+	th.AssertEquals(t, 0, sent)
+
+	// MaxCount is reached
+	request, _ = http.NewRequest("PUT", "http://swift.example.com/v1/AUTH_test/container/object", strings.NewReader("data"))
+	response, err = fi.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusOK, response.StatusCode)
+	th.AssertEquals(t, 1, sent)
+
+	request, _ = http.NewRequest("DELETE", "http://compute.example.com/servers/1", nil)
+	_, err = fi.RoundTrip(request)
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected a connection reset error, got %v", err)
+	}
+
+	request, _ = http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	response, err = fi.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, http.StatusUnauthorized, response.StatusCode)
+	th.AssertEquals(t, `Keystone uri="http://compute.example.com"`, response.Header.Get("WWW-Authenticate"))
+
+	request, _ = http.NewRequest("GET", "http://image.example.com/v2/images", nil)
+	response, err = fi.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	body, err := io.ReadAll(response.Body)
+	th.AssertEquals(t, io.ErrUnexpectedEOF, err)
+	th.AssertEquals(t, "0123", string(body))
+}
+
+func TestFaultInjectorTruncateShortBody(t *testing.T) {
+	fi := &FaultInjector{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			response := newTestResponse(r, http.StatusOK, nil)
+			response.Body = io.NopCloser(strings.NewReader("0123456789"))
+			return response, nil
+		}),
+		Rules: []FaultRule{
+			{Fault: Fault{TruncateBody: true, TruncateAfter: 10}},
+		},
+	}
+
+	// a body, which ends before the truncation point, is complete
+	request, _ := http.NewRequest("GET", "http://image.example.com/v2/images", nil)
+	response, err := fi.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	body, err := io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "0123456789", string(body))
+}
+
+func TestFaultInjectorProbability(t *testing.T) {
+	run := func() []int {
+		fi := &FaultInjector{
+			Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return newTestResponse(r, http.StatusOK, nil), nil
+			}),
+			Rules: []FaultRule{
+				{Probability: 0.5, Fault: Fault{StatusCode: http.StatusServiceUnavailable}},
+			},
+			Seed: 42,
+		}
+
+		var codes []int
+		for range 20 {
+			request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+			response, err := fi.RoundTrip(request)
+			th.AssertNoErr(t, err)
+			codes = append(codes, response.StatusCode)
+		}
+		return codes
+	}
+
+	first := run()
+	th.AssertDeepEquals(t, first, run())
+
+	var faults int
+	for _, code := range first {
+		if code == http.StatusServiceUnavailable {
+			faults++
+		}
+	}
+	if faults == 0 || faults == len(first) {
+		t.Fatalf("expected some faults to be injected, got %d of %d", faults, len(first))
+	}
+}
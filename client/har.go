@@ -0,0 +1,441 @@
+package client
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HARRecorder records HTTP requests and responses as a HAR 1.2 document.
+// Sensitive headers and body fields are masked the same way as in the
+// debug log. Since a HAR document is a single JSON object, it is written on
+// Close. Flush rewrites the document only, when the writer supports seeking
+// and truncating, e.g. an *os.File.
+//
+// An entry is recorded once its response body is read to the end or
+// closed. Responses, which bodies are still open, are not part of the
+// written document. The entries are kept in memory until the recorder is
+// closed, unless MaxEntries is set.
+type HARRecorder struct {
+	// MaxEntries limits the number of entries kept in memory. When the
+	// limit is exceeded, the oldest entries are dropped. If zero, all
+	// entries are kept.
+	MaxEntries int
+
+	w       io.Writer
+	mu      sync.Mutex
+	entries []harEntry
+	written bool
+	offset  int64
+	closed  bool
+}
+
+// truncater is implemented by writers, which can be rewritten, e.g.
+// *os.File.
+type truncater interface {
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// NewHARRecorder returns a new HARRecorder, which writes to w.
+func NewHARRecorder(w io.Writer) *HARRecorder {
+	return &HARRecorder{w: w}
+}
+
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harNameVal `json:"cookies"`
+	Headers     []harNameVal `json:"headers"`
+	QueryString []harNameVal `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harNameVal `json:"cookies"`
+	Headers     []harNameVal `json:"headers"`
+	Content     harBody      `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harNameVal struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harBody struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harTrace collects the timings of a request.
+type harTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func (t *harTrace) set(v *time.Time) func() {
+	return func() {
+		t.mu.Lock()
+		*v = time.Now()
+		t.mu.Unlock()
+	}
+}
+
+func (t *harTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.set(&t.dnsStart)() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone)() },
+		ConnectStart:         func(string, string) { t.set(&t.connectStart)() },
+		ConnectDone:          func(string, string, error) { t.set(&t.connectDone)() },
+		TLSHandshakeStart:    t.set(&t.tlsStart),
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone)() },
+		GotConn:              func(httptrace.GotConnInfo) { t.set(&t.gotConn)() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest)() },
+		GotFirstResponseByte: t.set(&t.firstByte),
+	}
+}
+
+// milliseconds returns the duration between two times or -1, when one of
+// them is unknown.
+func milliseconds(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return -1
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// timings returns the HAR timings of a request, which response has been
+// received at responseAt and which body has been read at doneAt.
+func (t *harTrace) timings(responseAt, doneAt time.Time) harTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := harTimings{
+		Blocked: -1,
+		DNS:     milliseconds(t.dnsStart, t.dnsDone),
+		Connect: milliseconds(t.connectStart, t.connectDone),
+		SSL:     milliseconds(t.tlsStart, t.tlsDone),
+	}
+
+	firstByte := t.firstByte
+	if firstByte.IsZero() {
+		firstByte = responseAt
+	}
+
+	sendStart := t.gotConn
+	if sendStart.IsZero() {
+		sendStart = t.start
+	}
+	if t.wroteRequest.IsZero() {
+		timings.Send = 0
+		timings.Wait = max(milliseconds(sendStart, firstByte), 0)
+	} else {
+		timings.Send = max(milliseconds(sendStart, t.wroteRequest), 0)
+		timings.Wait = max(milliseconds(t.wroteRequest, firstByte), 0)
+	}
+	timings.Receive = max(milliseconds(firstByte, doneAt), 0)
+
+	if !t.gotConn.IsZero() {
+		timings.Blocked = max(milliseconds(t.start, t.gotConn)-max(timings.DNS, 0)-max(timings.Connect, 0), 0)
+	}
+
+	return timings
+}
+
+func (timings harTimings) total() float64 {
+	var total float64
+	for _, v := range []float64{timings.Blocked, timings.DNS, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		total += max(v, 0)
+	}
+	return total
+}
+
+// harHeaders converts headers to sorted and masked HAR name value pairs.
+func (rt *RoundTripper) harHeaders(headers http.Header) []harNameVal {
+	result := make([]harNameVal, 0, len(headers))
+	for name, values := range headers {
+		for _, v := range values {
+			if rt.isSensitiveHeader(name) {
+				v = "***"
+			}
+			result = append(result, harNameVal{Name: name, Value: v})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// harStart starts recording a request. It returns a request, which carries
+// the client trace, and a function to be called with the response.
+func (rt *RoundTripper) harStart(har *HARRecorder, request *http.Request) (*http.Request, func(*http.Response, error) (*http.Response, error), error) {
+	trace := &harTrace{start: time.Now()}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace.clientTrace()))
+
+	query := request.URL.Query()
+	queryString := make([]harNameVal, 0, len(query))
+	for name, values := range query {
+		for _, v := range values {
+			queryString = append(queryString, harNameVal{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(queryString, func(i, j int) bool {
+		return queryString[i].Name < queryString[j].Name
+	})
+
+	entry := harEntry{
+		StartedDateTime: trace.start,
+		Request: harRequest{
+			Method:      request.Method,
+			URL:         request.URL.String(),
+			HTTPVersion: request.Proto,
+			Cookies:     []harNameVal{},
+			Headers:     rt.harHeaders(request.Header),
+			QueryString: queryString,
+			HeadersSize: -1,
+			BodySize:    request.ContentLength,
+		},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+
+	if request.Body != nil && request.Body != http.NoBody {
+		contentType := request.Header.Get("Content-Type")
+		text, body, truncated, err := rt.maskedBody(request.Body, contentType)
+		if err != nil {
+			return nil, nil, err
+		}
+		request.Body = body
+
+		entry.Request.PostData = &harPostData{
+			MimeType: contentType,
+			Text:     text,
+		}
+		if truncated {
+			entry.Request.PostData.Comment = "truncated"
+		}
+	} else {
+		entry.Request.BodySize = 0
+	}
+
+	finish := func(response *http.Response, err error) (*http.Response, error) {
+		responseAt := time.Now()
+
+		if response == nil {
+			entry.Response = harResponse{
+				Cookies:     []harNameVal{},
+				Headers:     []harNameVal{},
+				HTTPVersion: entry.Request.HTTPVersion,
+				HeadersSize: -1,
+				BodySize:    -1,
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			entry.Timings = trace.timings(responseAt, responseAt)
+			entry.Time = entry.Timings.total()
+			har.add(entry)
+			return response, err
+		}
+
+		contentType := response.Header.Get("Content-Type")
+		entry.Response = harResponse{
+			Status:      response.StatusCode,
+			StatusText:  http.StatusText(response.StatusCode),
+			HTTPVersion: response.Proto,
+			Cookies:     []harNameVal{},
+			Headers:     rt.harHeaders(response.Header),
+			Content: harBody{
+				MimeType: contentType,
+			},
+			HeadersSize: -1,
+		}
+		if entry.Response.HTTPVersion == "" {
+			entry.Response.HTTPVersion = "HTTP/1.1"
+		}
+
+		text, body, truncated, bodyErr := rt.maskedBody(response.Body, contentType)
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		entry.Response.Content.Text = text
+		if truncated {
+			entry.Response.Content.Comment = "truncated"
+		}
+
+		response.Body = newSummaryReader(body, func(n int64, _ []byte) {
+			entry.Response.Content.Size = n
+			entry.Response.BodySize = n
+			entry.Timings = trace.timings(responseAt, time.Now())
+			entry.Time = entry.Timings.total()
+			har.add(entry)
+		})
+
+		return response, err
+	}
+
+	return request, finish, nil
+}
+
+func (h *HARRecorder) add(entry harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if h.MaxEntries > 0 && len(h.entries) > h.MaxEntries {
+		h.entries = slices.Delete(h.entries, 0, len(h.entries)-h.MaxEntries)
+	}
+}
+
+// Flush writes the HAR document with all entries recorded so far, replacing
+// a previously written document. It does nothing, when the writer can not
+// be rewritten, since the document is written on Close.
+func (h *HARRecorder) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.w.(truncater); !ok || h.closed {
+		return nil
+	}
+
+	return h.write()
+}
+
+// write writes the HAR document. A previously written document is
+// truncated. The caller must hold the lock.
+func (h *HARRecorder) write() error {
+	if t, ok := h.w.(truncater); ok {
+		if !h.written {
+			offset, err := t.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			h.offset = offset
+		} else {
+			if _, err := t.Seek(h.offset, io.SeekStart); err != nil {
+				return err
+			}
+			if err := t.Truncate(h.offset); err != nil {
+				return err
+			}
+		}
+	} else if h.written {
+		return nil
+	}
+	h.written = true
+
+	entries := make([]harEntry, len(h.entries))
+	copy(entries, h.entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	doc := harLog{
+		Log: harContent{
+			Version: "1.2",
+			Creator: harCreator{
+				Name:    "gophercloud-utils",
+				Version: "v2",
+			},
+			Entries: entries,
+		},
+	}
+
+	enc := json.NewEncoder(h.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Close writes the HAR document. The underlying writer is not closed, so
+// that the caller has to close a file after Close. Entries recorded after
+// Close are discarded.
+func (h *HARRecorder) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+
+	err := h.write()
+	h.entries = nil
+
+	return err
+}
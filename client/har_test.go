@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Subject-Token", "secret-token")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"token": {"catalog": [{"type": "compute"}]}}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	har := NewHARRecorder(&buf)
+
+	rt := &RoundTripper{
+		Rt:  http.DefaultTransport,
+		HAR: har,
+	}
+
+	body := `{"auth": {"identity": {"password": {"user": {"name": "admin", "password": "secret"}}}}}`
+	request, _ := http.NewRequest("POST", server.URL+"/v3/auth/tokens?nocatalog=false", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	_, err = io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+	th.AssertNoErr(t, response.Body.Close())
+
+	failing := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		HAR: har,
+	}
+	request, _ = http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	_, err = failing.RoundTrip(request)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	th.AssertNoErr(t, har.Close())
+
+	var doc struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Time    float64 `json:"time"`
+				Request struct {
+					Method      string       `json:"method"`
+					Headers     []harNameVal `json:"headers"`
+					QueryString []harNameVal `json:"queryString"`
+					PostData    harPostData  `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int          `json:"status"`
+					Headers []harNameVal `json:"headers"`
+					Content harBody      `json:"content"`
+				} `json:"response"`
+				Timings harTimings `json:"timings"`
+				Error   string     `json:"_error"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	th.AssertNoErr(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	th.AssertEquals(t, "1.2", doc.Log.Version)
+	th.AssertEquals(t, 2, len(doc.Log.Entries))
+
+	entry := doc.Log.Entries[0]
+	th.AssertEquals(t, "POST", entry.Request.Method)
+	th.AssertDeepEquals(t, []harNameVal{{Name: "nocatalog", Value: "false"}}, entry.Request.QueryString)
+	if !strings.Contains(entry.Request.PostData.Text, `"password": "***"`) || strings.Contains(entry.Request.PostData.Text, "secret") {
+		t.Fatalf("request body is not masked: %s", entry.Request.PostData.Text)
+	}
+	th.AssertEquals(t, http.StatusCreated, entry.Response.Status)
+	th.AssertEquals(t, int64(45), entry.Response.Content.Size)
+	th.AssertEquals(t, true, strings.Contains(entry.Response.Content.Text, `"catalog": "***"`))
+	for _, h := range entry.Response.Headers {
+		if h.Name == "X-Subject-Token" {
+			th.AssertEquals(t, "***", h.Value)
+		}
+	}
+	if entry.Timings.Wait < 0 || entry.Time < 0 {
+		t.Fatalf("unexpected timings: %+v", entry.Timings)
+	}
+
+	th.AssertEquals(t, "GET", doc.Log.Entries[1].Request.Method)
+	th.AssertEquals(t, 0, doc.Log.Entries[1].Response.Status)
+	th.AssertEquals(t, true, strings.Contains(doc.Log.Entries[1].Error, "connection refused"))
+}
+
+func TestHARRecorderFlush(t *testing.T) {
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return newTestResponse(r, http.StatusOK, nil), nil
+		}),
+	}
+
+	get := func() {
+		request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		th.AssertNoErr(t, response.Body.Close())
+	}
+
+	entries := func(b []byte) int {
+		var doc harLog
+		th.AssertNoErr(t, json.Unmarshal(b, &doc))
+		return len(doc.Log.Entries)
+	}
+
+	// a file is rewritten on each flush
+	f, err := os.Create(filepath.Join(t.TempDir(), "session.har"))
+	th.AssertNoErr(t, err)
+	rt.HAR = NewHARRecorder(f)
+	rt.HAR.MaxEntries = 2
+
+	get()
+	th.AssertNoErr(t, rt.HAR.Flush())
+	get()
+	get()
+	th.AssertNoErr(t, rt.HAR.Flush())
+	th.AssertNoErr(t, rt.HAR.Close())
+
+	// the file is closed by its owner
+	th.AssertNoErr(t, f.Close())
+
+	b, err := os.ReadFile(f.Name())
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 2, entries(b))
+
+	// other writers are written on close only
+	var buf bytes.Buffer
+	rt.HAR = NewHARRecorder(&buf)
+
+	get()
+	th.AssertNoErr(t, rt.HAR.Flush())
+	th.AssertEquals(t, 0, buf.Len())
+	th.AssertNoErr(t, rt.HAR.Close())
+	th.AssertNoErr(t, rt.HAR.Close())
+	th.AssertEquals(t, 1, entries(buf.Bytes()))
+}
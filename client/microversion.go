@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/utils"
+)
+
+// ErrMicroversionUnavailable is returned, when the server doesn't support
+// the requested microversion range.
+type ErrMicroversionUnavailable struct {
+	Endpoint  string
+	Minimum   string
+	Maximum   string
+	Supported utils.SupportedMicroversions
+}
+
+func (e ErrMicroversionUnavailable) Error() string {
+	return fmt.Sprintf("microversion range %s - %s is not available at %s, supported range is %d.%d - %d.%d",
+		e.Minimum, e.Maximum, e.Endpoint,
+		e.Supported.MinMajor, e.Supported.MinMinor, e.Supported.MaxMajor, e.Supported.MaxMinor)
+}
+
+// MicroversionNegotiator negotiates microversions with OpenStack services and
+// caches the supported microversions per endpoint. When it is set as the
+// RoundTripper.Microversions, the negotiated microversion headers are
+// injected into requests to the negotiated endpoints, which have no
+// microversion set. A MicroversionNegotiator must not be copied after first
+// use.
+type MicroversionNegotiator struct {
+	mu         sync.Mutex
+	supported  map[string]utils.SupportedMicroversions
+	negotiated map[string]negotiatedMicroversion
+}
+
+// negotiatedMicroversion represents a microversion negotiated for an
+// endpoint.
+type negotiatedMicroversion struct {
+	serviceType string
+	version     string
+}
+
+// microversion represents a parsed microversion.
+type microversion struct {
+	major int
+	minor int
+}
+
+func (v microversion) less(o microversion) bool {
+	return v.major < o.major || (v.major == o.major && v.minor < o.minor)
+}
+
+func (v microversion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+func parseMicroversion(version string) (microversion, error) {
+	major, minor, err := utils.ParseMicroversion(version)
+	return microversion{major, minor}, err
+}
+
+// Negotiate returns the highest microversion supported by both the client
+// and the server within the minVersion and maxVersion range, and sets it as
+// the client.Microversion. An empty minVersion or maxVersion means no bound.
+// The supported microversions are fetched once per endpoint.
+func (n *MicroversionNegotiator) Negotiate(ctx context.Context, client *gophercloud.ServiceClient, minVersion, maxVersion string) (string, error) {
+	supported, err := n.getSupported(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	serverMin := microversion{supported.MinMajor, supported.MinMinor}
+	serverMax := microversion{supported.MaxMajor, supported.MaxMinor}
+
+	lower, upper := serverMin, serverMax
+	if minVersion != "" {
+		v, err := parseMicroversion(minVersion)
+		if err != nil {
+			return "", err
+		}
+		if lower.less(v) {
+			lower = v
+		}
+	}
+	if maxVersion != "" {
+		v, err := parseMicroversion(maxVersion)
+		if err != nil {
+			return "", err
+		}
+		if v.less(upper) {
+			upper = v
+		}
+	}
+
+	if upper.less(lower) {
+		return "", ErrMicroversionUnavailable{
+			Endpoint:  client.Endpoint,
+			Minimum:   minVersion,
+			Maximum:   maxVersion,
+			Supported: supported,
+		}
+	}
+
+	version := upper.String()
+
+	n.mu.Lock()
+	if n.negotiated == nil {
+		n.negotiated = make(map[string]negotiatedMicroversion)
+	}
+	n.negotiated[client.Endpoint] = negotiatedMicroversion{
+		serviceType: client.Type,
+		version:     version,
+	}
+	n.mu.Unlock()
+
+	client.Microversion = version
+
+	return version, nil
+}
+
+// Forget removes the cached and negotiated microversions of an endpoint.
+func (n *MicroversionNegotiator) Forget(endpoint string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.supported, endpoint)
+	delete(n.negotiated, endpoint)
+}
+
+func (n *MicroversionNegotiator) getSupported(ctx context.Context, client *gophercloud.ServiceClient) (utils.SupportedMicroversions, error) {
+	n.mu.Lock()
+	v, ok := n.supported[client.Endpoint]
+	n.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := utils.GetSupportedMicroversions(ctx, client)
+	if err != nil {
+		return v, fmt.Errorf("unable to determine supported microversions of %s: %w", client.Endpoint, err)
+	}
+
+	n.mu.Lock()
+	if n.supported == nil {
+		n.supported = make(map[string]utils.SupportedMicroversions)
+	}
+	n.supported[client.Endpoint] = v
+	n.mu.Unlock()
+
+	return v, nil
+}
+
+// setHeaders sets the negotiated microversion headers, when the request
+// targets a negotiated endpoint and has no microversion set.
+func (n *MicroversionNegotiator) setHeaders(request *http.Request) {
+	if request.Header.Get("OpenStack-API-Version") != "" {
+		return
+	}
+
+	u := request.URL.String()
+
+	n.mu.Lock()
+	var endpoint string
+	for k := range n.negotiated {
+		if strings.HasPrefix(u, k) && len(k) > len(endpoint) {
+			endpoint = k
+		}
+	}
+	v, ok := n.negotiated[endpoint]
+	n.mu.Unlock()
+
+	if !ok || v.serviceType == "" {
+		return
+	}
+
+	for k, h := range microversionHeaders(v.serviceType, v.version) {
+		if request.Header.Get(k) == "" {
+			request.Header[k] = h
+		}
+	}
+}
+
+// microversionHeaders returns the microversion headers of a service type the
+// same way as gophercloud.ServiceClient does.
+func microversionHeaders(serviceType, version string) http.Header {
+	headers := http.Header{}
+
+	switch serviceType {
+	case "compute":
+		headers.Set("X-OpenStack-Nova-API-Version", version)
+	case "shared-file-system", "sharev2", "share":
+		headers.Set("X-OpenStack-Manila-API-Version", version)
+	case "block-storage", "block-store", "volume", "volumev3":
+		headers.Set("X-OpenStack-Volume-API-Version", version)
+		serviceType = "volume"
+	case "baremetal":
+		headers.Set("X-OpenStack-Ironic-API-Version", version)
+	case "baremetal-introspection":
+		headers.Set("X-OpenStack-Ironic-Inspector-API-Version", version)
+	case "container-infrastructure-management", "container-infrastructure", "container-infra":
+		serviceType = "container-infra"
+	}
+
+	headers.Set("OpenStack-API-Version", serviceType+" "+version)
+
+	return headers
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+)
+
+const computeVersion = `
+{
+    "version": {
+        "id": "v2.1",
+        "status": "CURRENT",
+        "version": "2.90",
+        "min_version": "2.1",
+        "links": []
+    }
+}
+`
+
+func TestMicroversionNegotiator(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+
+	var calls int
+	fakeServer.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		th.TestMethod(t, r, "GET")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, computeVersion)
+	})
+
+	client := fake.ServiceClient(fakeServer)
+	client.Type = "compute"
+
+	n := &MicroversionNegotiator{}
+
+	v, err := n.Negotiate(context.TODO(), client, "2.60", "2.79")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "2.79", v)
+	th.AssertEquals(t, "2.79", client.Microversion)
+
+	v, err = n.Negotiate(context.TODO(), client, "2.60", "")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "2.90", v)
+
+	_, err = n.Negotiate(context.TODO(), client, "2.95", "")
+	var unavailable ErrMicroversionUnavailable
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected ErrMicroversionUnavailable, got %v", err)
+	}
+	th.AssertEquals(t, 2, unavailable.Supported.MaxMajor)
+	th.AssertEquals(t, 90, unavailable.Supported.MaxMinor)
+
+	// the version document is fetched once per endpoint
+	th.AssertEquals(t, 1, calls)
+
+	request, _ := http.NewRequest("GET", client.Endpoint+"servers", nil)
+	n.setHeaders(request)
+	th.AssertEquals(t, "compute 2.90", request.Header.Get("OpenStack-API-Version"))
+	th.AssertEquals(t, "2.90", request.Header.Get("X-OpenStack-Nova-API-Version"))
+
+	request, _ = http.NewRequest("GET", client.Endpoint+"servers", nil)
+	request.Header.Set("OpenStack-API-Version", "compute 2.1")
+	n.setHeaders(request)
+	th.AssertEquals(t, "compute 2.1", request.Header.Get("OpenStack-API-Version"))
+	th.AssertEquals(t, "", request.Header.Get("X-OpenStack-Nova-API-Version"))
+}
@@ -0,0 +1,278 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit represents a token bucket rate limit and a concurrency cap.
+type RateLimit struct {
+	// Rate is the number of requests per second. Zero means no rate limit.
+	Rate float64
+
+	// Burst is the maximum number of requests, which can be sent at once
+	// before the Rate applies. If zero, 1 is used.
+	Burst int
+
+	// MaxInFlight is the maximum number of concurrent requests. Zero means
+	// no concurrency limit.
+	MaxInFlight int
+}
+
+// RateLimiter limits the rate and the concurrency of requests per endpoint.
+// A RateLimiter must not be copied after first use.
+type RateLimiter struct {
+	// Default is the limit applied to requests, which method has no
+	// dedicated limit.
+	Default RateLimit
+
+	// Methods contains per HTTP method limits, e.g. "POST". A method limit
+	// replaces the Default limit and is tracked separately.
+	//
+	// Changes of Default and Methods apply to the following requests, but
+	// they must not be made concurrently with requests. Requests in flight
+	// do not count against a changed MaxInFlight.
+	Methods map[string]RateLimit
+
+	// Key returns the key requests are grouped by. If nil, requests are
+	// grouped by the URL host.
+	Key func(*http.Request) string
+
+	mu     sync.Mutex
+	states map[string]*limiterState
+}
+
+// limiterState represents a token bucket and a semaphore of a single key.
+type limiterState struct {
+	mu          sync.Mutex
+	limit       RateLimit
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	sem         chan struct{}
+}
+
+// EndpointKey returns a RateLimiter.Key function, which groups requests by
+// the longest matching endpoint, e.g. a service catalog URL. Requests not
+// matching any endpoint are grouped by their URL host.
+func EndpointKey(endpoints ...string) func(*http.Request) string {
+	return func(request *http.Request) string {
+		u := request.URL.String()
+
+		var key string
+		for _, e := range endpoints {
+			if strings.HasPrefix(u, e) && len(e) > len(key) {
+				key = e
+			}
+		}
+		if key == "" {
+			return request.URL.Host
+		}
+
+		return key
+	}
+}
+
+func (l *RateLimiter) state(request *http.Request) *limiterState {
+	var key string
+	if l.Key != nil {
+		key = l.Key(request)
+	} else {
+		key = request.URL.Host
+	}
+
+	// The method is case-sensitive in HTTP, but limits are configured per
+	// upper-case method.
+	method := strings.ToUpper(request.Method)
+
+	limit := l.Default
+	if v, ok := l.Methods[method]; ok {
+		limit = v
+		key = method + " " + key
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.states == nil {
+		l.states = make(map[string]*limiterState)
+	}
+
+	s, ok := l.states[key]
+	if !ok {
+		s = &limiterState{
+			tokens: float64(max(limit.Burst, 1)),
+			last:   time.Now(),
+		}
+		l.states[key] = s
+	}
+	s.setLimit(limit)
+
+	return s
+}
+
+// Wait blocks until the request is allowed to be sent or the request context
+// is done. The returned function must be called once the request has
+// completed.
+func (l *RateLimiter) Wait(request *http.Request) (func(), error) {
+	ctx := request.Context()
+	s := l.state(request)
+
+	if wait := s.reserve(time.Now()); wait > 0 {
+		if err := waitContext(ctx, wait); err != nil {
+			s.cancel()
+			return nil, fmt.Errorf("rate limit wait aborted: %w", err)
+		}
+	}
+
+	sem := s.semaphore()
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("concurrency limit wait aborted: %w", ctx.Err())
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-sem })
+	}, nil
+}
+
+// Observe pauses all requests of the response's key until the time
+// specified in the Retry-After header of a 429 or 503 response.
+func (l *RateLimiter) Observe(request *http.Request, response *http.Response) {
+	if response == nil {
+		return
+	}
+
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+
+	sleep, ok := parseRetryAfter(response.Header.Get("Retry-After"))
+	if !ok || sleep <= 0 {
+		return
+	}
+
+	l.state(request).pause(time.Now().Add(sleep))
+}
+
+// setLimit updates the limit of the state. The semaphore is replaced, when
+// MaxInFlight has changed.
+func (s *limiterState) setLimit(limit RateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sem == nil || limit.MaxInFlight != s.limit.MaxInFlight {
+		s.sem = nil
+		if limit.MaxInFlight > 0 {
+			s.sem = make(chan struct{}, limit.MaxInFlight)
+		}
+	}
+	s.limit = limit
+}
+
+func (s *limiterState) semaphore() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sem
+}
+
+// releaseBody wraps the body of a response, so that release is called once
+// the body is read to the end or closed. Responses without a body are
+// released immediately.
+func releaseBody(response *http.Response, release func()) {
+	if response == nil || response.Body == nil || response.Body == http.NoBody {
+		release()
+		return
+	}
+
+	response.Body = &releaseReader{rc: response.Body, release: release}
+}
+
+// releaseReader calls release once the body is read to the end or closed.
+type releaseReader struct {
+	rc      io.ReadCloser
+	release func()
+}
+
+func (r *releaseReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if err == io.EOF {
+		r.release()
+	}
+	return n, err
+}
+
+func (r *releaseReader) Close() error {
+	err := r.rc.Close()
+	r.release()
+	return err
+}
+
+// reserve takes a token from the bucket and returns the time to wait until
+// the token becomes available.
+func (s *limiterState) reserve(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var wait time.Duration
+	if s.pausedUntil.After(now) {
+		wait = s.pausedUntil.Sub(now)
+	}
+
+	if s.limit.Rate > 0 {
+		burst := float64(max(s.limit.Burst, 1))
+		s.tokens = min(burst, s.tokens+now.Sub(s.last).Seconds()*s.limit.Rate)
+		s.last = now
+		s.tokens--
+		if s.tokens < 0 {
+			wait = max(wait, time.Duration(-s.tokens/s.limit.Rate*float64(time.Second)))
+		}
+	}
+
+	return wait
+}
+
+// cancel returns a reserved token to the bucket.
+func (s *limiterState) cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limit.Rate > 0 {
+		s.tokens++
+	}
+}
+
+func (s *limiterState) pause(until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+// waitContext sleeps for the specified duration or until the context is
+// done.
+func waitContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func newTestResponse(request *http.Request, code int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: code,
+		Header:     header,
+		Body:       http.NoBody,
+		Request:    request,
+	}
+}
+
+func TestRateLimiterRate(t *testing.T) {
+	limiter := &RateLimiter{
+		Default: RateLimit{Rate: 50, Burst: 1},
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return newTestResponse(r, http.StatusOK, nil), nil
+		}),
+		RateLimiter: limiter,
+	}
+
+	start := time.Now()
+	for range 3 {
+		request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+		_, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+	}
+
+	// the first request consumes the burst, the other two wait 20ms each
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("expected requests to be delayed, elapsed %s", elapsed)
+	}
+}
+
+func TestRateLimiterMaxInFlight(t *testing.T) {
+	limiter := &RateLimiter{
+		Methods: map[string]RateLimit{
+			"POST": {MaxInFlight: 1},
+		},
+	}
+
+	block := make(chan struct{})
+	var inFlight atomic.Int32
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			inFlight.Add(1)
+			select {
+			case <-block:
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			}
+			return newTestResponse(r, http.StatusAccepted, nil), nil
+		}),
+		RateLimiter: limiter,
+	}
+
+	go func() {
+		request, _ := http.NewRequest("POST", "http://compute.example.com/servers", strings.NewReader(""))
+		_, _ = rt.RoundTrip(request)
+	}()
+
+	for inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the limit is shared by the same method in any case
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, "post", "http://compute.example.com/servers", strings.NewReader(""))
+	_, err := rt.RoundTrip(request)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+	th.AssertEquals(t, int32(1), inFlight.Load())
+
+	// GET requests are not limited
+	request, _ = http.NewRequest("GET", "http://compute.example.com/servers", nil)
+	go func() {
+		_, _ = rt.RoundTrip(request)
+	}()
+	for inFlight.Load() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(block)
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	limiter := &RateLimiter{
+		Key: EndpointKey("http://cloud.example.com/compute", "http://cloud.example.com/network"),
+	}
+
+	request, _ := http.NewRequest("GET", "http://cloud.example.com/compute/servers", nil)
+	limiter.Observe(request, newTestResponse(request, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}}))
+
+	// other endpoints are not affected
+	request, _ = http.NewRequest("GET", "http://cloud.example.com/network/v2.0/ports", nil)
+	release, err := limiter.Wait(request)
+	th.AssertNoErr(t, err)
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	request, _ = http.NewRequestWithContext(ctx, "GET", "http://cloud.example.com/compute/flavors", nil)
+	_, err = limiter.Wait(request)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+}
+
+func TestRateLimiterMaxInFlightBody(t *testing.T) {
+	limiter := &RateLimiter{
+		Default: RateLimit{MaxInFlight: 1},
+	}
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			response := newTestResponse(r, http.StatusOK, nil)
+			response.Body = io.NopCloser(strings.NewReader("data"))
+			return response, nil
+		}),
+		RateLimiter: limiter,
+	}
+
+	request, _ := http.NewRequest("GET", "http://object.example.com/container/object", nil)
+	response, err := rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+
+	// the slot is held, while the body is streamed
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	request, _ = http.NewRequestWithContext(ctx, "GET", "http://object.example.com/container/object", nil)
+	_, err = rt.RoundTrip(request)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+
+	_, err = io.ReadAll(response.Body)
+	th.AssertNoErr(t, err)
+
+	request, _ = http.NewRequest("GET", "http://object.example.com/container/object", nil)
+	response, err = rt.RoundTrip(request)
+	th.AssertNoErr(t, err)
+	th.AssertNoErr(t, response.Body.Close())
+
+	// a changed limit applies to the following requests
+	limiter.Default = RateLimit{MaxInFlight: 2}
+	for range 2 {
+		request, _ = http.NewRequest("GET", "http://object.example.com/container/object", nil)
+		_, err = rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+	}
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack"
+	tokens2 "github.com/gophercloud/gophercloud/v2/openstack/identity/v2/tokens"
+	tokens3 "github.com/gophercloud/gophercloud/v2/openstack/identity/v3/tokens"
+)
+
+// DefaultTokenRefreshMargin is the default time before the token expiration,
+// when the token is refreshed.
+const DefaultTokenRefreshMargin = 2 * time.Minute
+
+// maxStaleTokens is the maximum number of replaced tokens to be remembered.
+const maxStaleTokens = 16
+
+// TokenRefresher refreshes the X-Auth-Token of requests, which token is
+// about to expire, and retries requests, which have been rejected with 401
+// Unauthorized, once with a refreshed token. Concurrent requests share a
+// single refresh. Requests carrying a token, which has been replaced by a
+// refresh, are sent with the refreshed token. A TokenRefresher must not be
+// copied after first use.
+type TokenRefresher struct {
+	// Refresh obtains a new token and its expiration time. A zero
+	// expiration time means that it is unknown.
+	Refresh func(ctx context.Context) (token string, expiresAt time.Time, err error)
+
+	// ExpiresAt returns the expiration time of a token, which has not been
+	// obtained by Refresh. A zero time means that it is unknown. If
+	// ExpiresAt is nil, such tokens are only refreshed on 401 responses.
+	ExpiresAt func(token string) time.Time
+
+	// Margin is the time before the token expiration, when the token is
+	// refreshed. If zero, DefaultTokenRefreshMargin is used.
+	Margin time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	stale     map[string]struct{}
+	ongoing   *tokenRefresh
+}
+
+// tokenRefresh represents a refresh in progress.
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func (r *TokenRefresher) margin() time.Duration {
+	if r.Margin > 0 {
+		return r.Margin
+	}
+	return DefaultTokenRefreshMargin
+}
+
+// current returns the token to be used instead of the requested one and
+// whether it is about to expire. It must be called with the lock held.
+func (r *TokenRefresher) current(token string) (string, bool) {
+	if _, ok := r.stale[token]; !ok && token != r.token {
+		// the token has been obtained elsewhere, e.g. by a ReauthFunc
+		r.token = token
+		r.expiresAt = time.Time{}
+		if r.ExpiresAt != nil {
+			r.expiresAt = r.ExpiresAt(token)
+		}
+	}
+
+	expiring := !r.expiresAt.IsZero() && time.Until(r.expiresAt) < r.margin()
+	return r.token, expiring
+}
+
+// prepare replaces the request token with the current one and refreshes it,
+// when it is about to expire.
+func (r *TokenRefresher) prepare(request *http.Request) error {
+	token := request.Header.Get("X-Auth-Token")
+	if token == "" {
+		// authentication requests carry no token
+		return nil
+	}
+
+	r.mu.Lock()
+	current, expiring := r.current(token)
+	expiresAt := r.expiresAt
+	r.mu.Unlock()
+
+	if expiring {
+		refreshed, err := r.refresh(request.Context(), current)
+		if err != nil {
+			if time.Now().After(expiresAt) {
+				return err
+			}
+			// the current token is still valid, try again with the next
+			// request
+		} else {
+			current = refreshed
+		}
+	}
+
+	request.Header.Set("X-Auth-Token", current)
+
+	return nil
+}
+
+// refresh obtains a new token to replace the previous one. When the
+// previous token has already been replaced, the current token is returned.
+// A refresh in progress is shared by all callers.
+func (r *TokenRefresher) refresh(ctx context.Context, previous string) (string, error) {
+	r.mu.Lock()
+	if _, ok := r.stale[previous]; ok && r.token != "" {
+		token := r.token
+		r.mu.Unlock()
+		return token, nil
+	}
+
+	if call := r.ongoing; call != nil {
+		r.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.token, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	call := &tokenRefresh{done: make(chan struct{})}
+	r.ongoing = call
+	r.mu.Unlock()
+
+	// the refresh is shared, so it must not be canceled with the request
+	// which started it
+	token, expiresAt, err := r.Refresh(context.WithoutCancel(ctx))
+
+	r.mu.Lock()
+	if err == nil {
+		if r.stale == nil || len(r.stale) >= maxStaleTokens {
+			r.stale = make(map[string]struct{})
+		}
+		r.stale[previous] = struct{}{}
+		if r.token != previous {
+			r.stale[r.token] = struct{}{}
+		}
+		r.token = token
+		r.expiresAt = expiresAt
+	}
+	call.token, call.err = token, err
+	r.ongoing = nil
+	r.mu.Unlock()
+	close(call.done)
+
+	return token, err
+}
+
+// retryUnauthorized refreshes the token and returns a copy of the request
+// carrying it, when the response is 401 Unauthorized. It returns nil, when
+// the request cannot be retried.
+func (r *TokenRefresher) retryUnauthorized(request *http.Request, response *http.Response) (*http.Request, error) {
+	if response.StatusCode != http.StatusUnauthorized {
+		return nil, nil
+	}
+
+	token := request.Header.Get("X-Auth-Token")
+	if token == "" {
+		return nil, nil
+	}
+
+	hasBody := request.Body != nil && request.Body != http.NoBody
+	if hasBody && request.GetBody == nil {
+		return nil, nil
+	}
+
+	refreshed, err := r.refresh(request.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := request.Clone(request.Context())
+	retry.Header.Set("X-Auth-Token", refreshed)
+	if hasBody {
+		retry.Body, err = request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, _ = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	return retry, nil
+}
+
+// NewProviderTokenRefresher returns a TokenRefresher, which authenticates
+// with the specified options and copies the new token into the provider
+// client. The options may contain any credentials supported by
+// openstack.Authenticate, including application credentials returned by
+// clientconfig.AuthOptions. The provider client must have authenticated
+// already or have the AuthResult set.
+func NewProviderTokenRefresher(provider *gophercloud.ProviderClient, opts gophercloud.AuthOptions) *TokenRefresher {
+	// the ReauthFunc of a throwaway provider client would be useless
+	opts.AllowReauth = false
+
+	return &TokenRefresher{
+		Refresh: func(ctx context.Context) (string, time.Time, error) {
+			tmp, err := openstack.NewClient(opts.IdentityEndpoint)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			tmp.HTTPClient = provider.HTTPClient
+			tmp.UserAgent = provider.UserAgent
+
+			err = openstack.Authenticate(ctx, tmp, opts)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+
+			provider.CopyTokenFrom(tmp)
+
+			return tmp.Token(), TokenExpiresAt(tmp.GetAuthResult()), nil
+		},
+		ExpiresAt: func(token string) time.Time {
+			if provider.Token() != token {
+				return time.Time{}
+			}
+			return TokenExpiresAt(provider.GetAuthResult())
+		},
+	}
+}
+
+// TokenExpiresAt returns the expiration time of the token returned by an
+// Identity v2 or v3 authentication request. It returns a zero time, when it
+// is unknown.
+func TokenExpiresAt(result gophercloud.AuthResult) time.Time {
+	switch r := result.(type) {
+	case interface {
+		ExtractToken() (*tokens3.Token, error)
+	}:
+		if token, err := r.ExtractToken(); err == nil {
+			return token.ExpiresAt
+		}
+	case interface {
+		ExtractToken() (*tokens2.Token, error)
+	}:
+		if token, err := r.ExtractToken(); err == nil {
+			return token.ExpiresAt
+		}
+	}
+
+	return time.Time{}
+}
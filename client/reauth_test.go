@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestTokenRefresherExpiring(t *testing.T) {
+	var refreshes int32
+	refresher := &TokenRefresher{
+		Refresh: func(ctx context.Context) (string, time.Time, error) {
+			atomic.AddInt32(&refreshes, 1)
+			time.Sleep(10 * time.Millisecond)
+			return "token2", time.Now().Add(time.Hour), nil
+		},
+		ExpiresAt: func(token string) time.Time {
+			th.AssertEquals(t, "token1", token)
+			return time.Now().Add(time.Minute)
+		},
+	}
+
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			th.AssertEquals(t, "token2", r.Header.Get("X-Auth-Token"))
+			return newTestResponse(r, http.StatusOK, nil), nil
+		}),
+		TokenRefresher: refresher,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request, _ := http.NewRequest("GET", "http://compute.example.com/servers", nil)
+			request.Header.Set("X-Auth-Token", "token1")
+			_, err := rt.RoundTrip(request)
+			th.AssertNoErr(t, err)
+		}()
+	}
+	wg.Wait()
+
+	th.AssertEquals(t, int32(1), atomic.LoadInt32(&refreshes))
+}
+
+func TestTokenRefresherUnauthorized(t *testing.T) {
+	var refreshes int
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(r.Body)
+			th.AssertNoErr(t, err)
+			th.AssertEquals(t, `{"server": {}}`, string(body))
+
+			if r.Header.Get("X-Auth-Token") != "token2" {
+				return newTestResponse(r, http.StatusUnauthorized, nil), nil
+			}
+			return newTestResponse(r, http.StatusAccepted, nil), nil
+		}),
+		TokenRefresher: &TokenRefresher{
+			Refresh: func(ctx context.Context) (string, time.Time, error) {
+				refreshes++
+				return "token2", time.Time{}, nil
+			},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		request, _ := http.NewRequest("POST", "http://compute.example.com/servers", strings.NewReader(`{"server": {}}`))
+		request.Header.Set("X-Auth-Token", "token1")
+		response, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, http.StatusAccepted, response.StatusCode)
+	}
+
+	// the replaced token is not refreshed again
+	th.AssertEquals(t, 1, refreshes)
+}
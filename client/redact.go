@@ -0,0 +1,262 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RedactionRule describes a JSON field, which value must be masked before a
+// request or response body is logged.
+type RedactionRule struct {
+	// Path is a dot-separated path to a field starting at the root of the
+	// document, e.g. "auth.identity.password.user.password". A "*" element
+	// matches any object key and a "[]" element descends into every item of
+	// an array, e.g. "servers[].adminPass" or "[].password".
+	Path string
+
+	// Key is a case-insensitive key name pattern, which is matched at any
+	// depth of the document, e.g. "adminPass" or "*_password". The pattern
+	// syntax is the one of path.Match. Key is ignored when Path is set.
+	Key string
+
+	// MaxDepth limits the Key matching to objects nested at most MaxDepth
+	// levels deep. The root object has a depth of 1. Zero means no limit.
+	MaxDepth int
+
+	// Mask is an optional function, which is called with the object holding
+	// the matched key. When Mask is nil, the value is replaced with "***".
+	Mask func(parent map[string]any, key string)
+}
+
+// Redactor masks sensitive fields in JSON documents according to a set of
+// redaction rules.
+type Redactor struct {
+	rules []redactionRule
+}
+
+// redactionRule is a parsed RedactionRule.
+type redactionRule struct {
+	RedactionRule
+	steps []string
+	key   string
+}
+
+var defaultRedactionRules = []RedactionRule{
+	// v2 auth methods
+	{Path: "auth.passwordCredentials.password"},
+	{Path: "auth.token.id"},
+	// v3 auth methods
+	{Path: "auth.identity.password.user.password"},
+	{Path: "auth.identity.application_credential.secret"},
+	{Path: "auth.identity.token.id"},
+	{Path: "auth.identity.totp.user.passcode"},
+	// EC2 access id and body hash
+	{Path: "credentials", Mask: maskEC2Credentials},
+	// Ignore the huge catalog output
+	{Path: "token.catalog"},
+	// Identity users, application and EC2 credentials
+	{Path: "user.password"},
+	{Path: "user.original_password"},
+	{Path: "application_credential.secret"},
+	{Path: "credential.secret"},
+	{Path: "credential.blob"},
+	// Compute server create, rebuild and password change
+	{Key: "adminPass"},
+	{Key: "user_data"},
+	// Database instance users
+	{Path: "instance.users[].password"},
+	{Path: "users[].password"},
+	// Key Manager secrets
+	{Key: "payload", MaxDepth: 1},
+}
+
+// defaultRedactor is used, when no custom Redactor was set.
+var defaultRedactor = NewRedactor(defaultRedactionRules...)
+
+// DefaultRedactionRules returns the default list of rules, which cover the
+// known OpenStack secret fields. The result can be extended and passed to
+// NewRedactor.
+func DefaultRedactionRules() []RedactionRule {
+	rules := make([]RedactionRule, len(defaultRedactionRules))
+	copy(rules, defaultRedactionRules)
+	return rules
+}
+
+// NewRedactor returns a new Redactor, which applies the specified rules in
+// their order.
+func NewRedactor(rules ...RedactionRule) *Redactor {
+	r := &Redactor{
+		rules: make([]redactionRule, 0, len(rules)),
+	}
+
+	for _, rule := range rules {
+		v := redactionRule{RedactionRule: rule}
+		if rule.Path != "" {
+			v.steps = parseRedactionPath(rule.Path)
+		} else if rule.Key != "" {
+			v.key = strings.ToLower(rule.Key)
+		} else {
+			continue
+		}
+		r.rules = append(r.rules, v)
+	}
+
+	return r
+}
+
+// parseRedactionPath splits a redaction path into steps, e.g.
+// "servers[].adminPass" becomes ["servers", "[]", "adminPass"].
+func parseRedactionPath(p string) []string {
+	var steps []string
+	for _, v := range strings.Split(p, ".") {
+		name, isArray := strings.CutSuffix(v, "[]")
+		if name != "" {
+			steps = append(steps, name)
+		}
+		if isArray {
+			steps = append(steps, "[]")
+		}
+	}
+	return steps
+}
+
+// Redact masks the sensitive fields of an unmarshalled JSON document in
+// place.
+func (r *Redactor) Redact(data any) {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.steps != nil {
+			rule.redactPath(data, rule.steps)
+		} else {
+			rule.redactKey(data, 1)
+		}
+	}
+}
+
+// MatchKey reports whether a key name matches one of the Key rules, which
+// apply at the root level. It is used to mask flat structures, e.g. form
+// fields.
+func (r *Redactor) MatchKey(key string) bool {
+	for i := range r.rules {
+		if r.rules[i].matchKey(key, 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatJSON pretty-formats a JSON body and masks its sensitive fields. It
+// satisfies the RoundTripper.FormatJSON signature.
+func (r *Redactor) FormatJSON(raw []byte) (string, error) {
+	var data any
+
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return string(raw), fmt.Errorf("unable to parse OpenStack JSON: %s", err)
+	}
+
+	r.Redact(data)
+
+	pretty, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return string(raw), fmt.Errorf("unable to re-marshal OpenStack JSON: %s", err)
+	}
+
+	return string(pretty), nil
+}
+
+func (rule *redactionRule) mask(parent map[string]any, key string) {
+	if rule.Mask != nil {
+		rule.Mask(parent, key)
+		return
+	}
+	parent[key] = "***"
+}
+
+func (rule *redactionRule) redactPath(data any, steps []string) {
+	last := len(steps) == 1
+
+	if steps[0] == "[]" {
+		items, ok := data.([]any)
+		if !ok {
+			return
+		}
+		for i := range items {
+			if last {
+				items[i] = "***"
+				continue
+			}
+			rule.redactPath(items[i], steps[1:])
+		}
+		return
+	}
+
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+
+	for k, v := range obj {
+		if steps[0] != "*" && steps[0] != k {
+			continue
+		}
+		if last {
+			rule.mask(obj, k)
+			continue
+		}
+		rule.redactPath(v, steps[1:])
+	}
+}
+
+func (rule *redactionRule) matchKey(key string, depth int) bool {
+	if rule.key == "" || (rule.MaxDepth > 0 && depth > rule.MaxDepth) {
+		return false
+	}
+	ok, _ := path.Match(rule.key, strings.ToLower(key))
+	return ok
+}
+
+func (rule *redactionRule) redactKey(data any, depth int) {
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			rule.redactKey(item, depth)
+		}
+	case map[string]any:
+		if rule.MaxDepth > 0 && depth > rule.MaxDepth {
+			return
+		}
+		for k, item := range v {
+			if rule.matchKey(k, depth) {
+				rule.mask(v, k)
+				continue
+			}
+			rule.redactKey(item, depth+1)
+		}
+	}
+}
+
+// maskEC2Credentials masks the EC2 access id and body hash, including the
+// access id mentioned in the signed Authorization header.
+func maskEC2Credentials(parent map[string]any, key string) {
+	v, ok := parent[key].(map[string]any)
+	if !ok {
+		return
+	}
+
+	var access string
+	if s, ok := v["access"]; ok {
+		access, _ = s.(string)
+		v["access"] = "***"
+	}
+	if _, ok := v["body_hash"]; ok {
+		v["body_hash"] = "***"
+	}
+	if v, ok := v["headers"].(map[string]any); ok {
+		if s, ok := v["Authorization"].(string); ok && access != "" {
+			v["Authorization"] = strings.ReplaceAll(s, access, "***")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func formatAndDecode(t *testing.T, r *Redactor, raw string) map[string]any {
+	t.Helper()
+
+	formatted, err := r.FormatJSON([]byte(raw))
+	th.AssertNoErr(t, err)
+
+	var data map[string]any
+	th.AssertNoErr(t, json.Unmarshal([]byte(formatted), &data))
+
+	return data
+}
+
+func TestFormatJSONDefaultRules(t *testing.T) {
+	raw := `{"auth": {"identity": {"methods": ["password"], "password": {"user": {"name": "admin", "password": "secret"}}}}}`
+	formatted, err := FormatJSON([]byte(raw))
+	th.AssertNoErr(t, err)
+
+	expected := `{
+  "auth": {
+    "identity": {
+      "methods": [
+        "password"
+      ],
+      "password": {
+        "user": {
+          "name": "admin",
+          "password": "***"
+        }
+      }
+    }
+  }
+}`
+	th.AssertEquals(t, expected, formatted)
+
+	data := formatAndDecode(t, defaultRedactor, `{"server": {"name": "vm", "adminPass": "secret", "user_data": "IyEvYmluL3No"}}`)
+	server := data["server"].(map[string]any)
+	th.AssertEquals(t, "vm", server["name"])
+	th.AssertEquals(t, "***", server["adminPass"])
+	th.AssertEquals(t, "***", server["user_data"])
+
+	data = formatAndDecode(t, defaultRedactor, `{"name": "key", "payload": "secret", "metadata": {"payload": "visible"}}`)
+	th.AssertEquals(t, "***", data["payload"])
+	th.AssertEquals(t, "visible", data["metadata"].(map[string]any)["payload"])
+
+	data = formatAndDecode(t, defaultRedactor, `{"credentials": {"access": "181920", "body_hash": "abc", "headers": {"Authorization": "AWS4-HMAC-SHA256 Credential=181920/20240101"}}}`)
+	credentials := data["credentials"].(map[string]any)
+	th.AssertEquals(t, "***", credentials["access"])
+	th.AssertEquals(t, "***", credentials["body_hash"])
+	th.AssertEquals(t, "AWS4-HMAC-SHA256 Credential=***/20240101", credentials["headers"].(map[string]any)["Authorization"])
+}
+
+func TestRedactorCustomRules(t *testing.T) {
+	r := NewRedactor(append(DefaultRedactionRules(),
+		RedactionRule{Path: "items[].*.token"},
+		RedactionRule{Key: "*_secret", MaxDepth: 2},
+	)...)
+
+	raw := `{
+		"items": [{"a": {"token": "t1"}}, {"b": {"token": "t2", "id": "b"}}],
+		"client_secret": "s1",
+		"nested": {"api_secret": "s2", "deeper": {"db_secret": "s3"}}
+	}`
+	data := formatAndDecode(t, r, raw)
+
+	items := data["items"].([]any)
+	th.AssertEquals(t, "***", items[0].(map[string]any)["a"].(map[string]any)["token"])
+	th.AssertEquals(t, "***", items[1].(map[string]any)["b"].(map[string]any)["token"])
+	th.AssertEquals(t, "b", items[1].(map[string]any)["b"].(map[string]any)["id"])
+
+	th.AssertEquals(t, "***", data["client_secret"])
+	nested := data["nested"].(map[string]any)
+	th.AssertEquals(t, "***", nested["api_secret"])
+	th.AssertEquals(t, "s3", nested["deeper"].(map[string]any)["db_secret"])
+
+	th.AssertEquals(t, true, r.MatchKey("CLIENT_SECRET"))
+	th.AssertEquals(t, false, r.MatchKey("name"))
+}
+
+func TestRoundTripperRedactor(t *testing.T) {
+	rt := RoundTripper{
+		Redactor: NewRedactor(RedactionRule{Key: "name"}),
+	}
+
+	formatted, err := rt.formatJSON()([]byte(`{"name": "vm", "adminPass": "secret"}`))
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "{\n  \"adminPass\": \"secret\",\n  \"name\": \"***\"\n}", formatted)
+}
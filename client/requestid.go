@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// requestIDHeader is the header, which carries the global request ID.
+const requestIDHeader = "X-OpenStack-Request-ID"
+
+// responseRequestIDHeaders is a list of headers, which contain the request ID
+// assigned by a service.
+var responseRequestIDHeaders = []string{
+	"X-Openstack-Request-Id",
+	"X-Compute-Request-Id",
+	"X-Trans-Id",
+}
+
+type requestIDKey struct{}
+
+type requestIDCollectorKey struct{}
+
+// NewRequestID generates a new global request ID in the "req-<uuid>" format
+// expected by OpenStack services.
+func NewRequestID() string {
+	return "req-" + uuid.Must(uuid.NewV4()).String()
+}
+
+// WithRequestID returns a copy of the context, which carries the global
+// request ID. The ID is sent in the X-OpenStack-Request-ID header of every
+// request made under the returned context. OpenStack services ignore IDs,
+// which are not in the "req-<uuid>" format.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the global request ID carried by the context.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestIDEntry represents the request ID of a single response.
+type RequestIDEntry struct {
+	Method     string
+	URL        string
+	StatusCode int
+	RequestID  string
+}
+
+// RequestIDCollector collects the request IDs of the responses to requests
+// made under a context.
+type RequestIDCollector struct {
+	mu      sync.Mutex
+	entries []RequestIDEntry
+}
+
+// WithRequestIDCollector returns a copy of the context, which carries the
+// request ID collector.
+func WithRequestIDCollector(ctx context.Context, c *RequestIDCollector) context.Context {
+	return context.WithValue(ctx, requestIDCollectorKey{}, c)
+}
+
+// RequestIDCollectorFromContext returns the request ID collector carried by
+// the context.
+func RequestIDCollectorFromContext(ctx context.Context) (*RequestIDCollector, bool) {
+	c, ok := ctx.Value(requestIDCollectorKey{}).(*RequestIDCollector)
+	return c, ok && c != nil
+}
+
+// Entries returns the collected request IDs in the order of responses.
+func (c *RequestIDCollector) Entries() []RequestIDEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]RequestIDEntry, len(c.entries))
+	copy(entries, c.entries)
+	return entries
+}
+
+// RequestIDs returns the collected request IDs.
+func (c *RequestIDCollector) RequestIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]string, 0, len(c.entries))
+	for _, e := range c.entries {
+		ids = append(ids, e.RequestID)
+	}
+	return ids
+}
+
+func (c *RequestIDCollector) add(e RequestIDEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = append(c.entries, e)
+}
+
+// setRequestID sets the global request ID header from the request context.
+func setRequestID(request *http.Request) {
+	if request.Header.Get(requestIDHeader) != "" {
+		return
+	}
+
+	if id, ok := RequestIDFromContext(request.Context()); ok {
+		request.Header.Set(requestIDHeader, id)
+	}
+}
+
+// collectRequestID stores the request ID of the response in the collector
+// carried by the request context.
+func collectRequestID(request *http.Request, response *http.Response) {
+	c, ok := RequestIDCollectorFromContext(request.Context())
+	if !ok {
+		return
+	}
+
+	id := responseRequestID(response)
+	if id == "" {
+		return
+	}
+
+	c.add(RequestIDEntry{
+		Method:     request.Method,
+		URL:        request.URL.String(),
+		StatusCode: response.StatusCode,
+		RequestID:  id,
+	})
+}
+
+// responseRequestID returns the request ID assigned by the service.
+func responseRequestID(response *http.Response) string {
+	for _, h := range responseRequestIDHeaders {
+		if id := response.Header.Get(h); id != "" {
+			return id
+		}
+	}
+	return ""
+}
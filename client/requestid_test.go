@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	id := NewRequestID()
+	if !strings.HasPrefix(id, "req-") || len(id) != 40 {
+		t.Fatalf("unexpected request ID format: %s", id)
+	}
+
+	rt := &RoundTripper{
+		Rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			th.AssertEquals(t, id, r.Header.Get("X-OpenStack-Request-ID"))
+			header := http.Header{}
+			if strings.Contains(r.URL.Host, "compute") {
+				header.Set("X-Compute-Request-Id", "req-compute")
+			} else {
+				header.Set("X-Openstack-Request-Id", "req-network")
+			}
+			return newTestResponse(r, http.StatusOK, header), nil
+		}),
+	}
+
+	collector := &RequestIDCollector{}
+	ctx := WithRequestIDCollector(WithRequestID(context.Background(), id), collector)
+
+	for _, u := range []string{"http://compute.example.com/servers", "http://network.example.com/v2.0/ports"} {
+		request, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
+		_, err := rt.RoundTrip(request)
+		th.AssertNoErr(t, err)
+	}
+
+	th.AssertDeepEquals(t, []string{"req-compute", "req-network"}, collector.RequestIDs())
+	th.AssertDeepEquals(t, RequestIDEntry{
+		Method:     "GET",
+		URL:        "http://compute.example.com/servers",
+		StatusCode: http.StatusOK,
+		RequestID:  "req-compute",
+	}, collector.Entries()[0])
+}
@@ -0,0 +1,247 @@
+package objects
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+)
+
+// UploadArchiveOpts represents options used for uploading an archive.
+type UploadArchiveOpts struct {
+	// BandwidthLimiter limits the bandwidth of the upload.
+	BandwidthLimiter *BandwidthLimiter
+
+	// Content is an archive in the specified format.
+	Content io.Reader
+
+	// Format is the format of the archive, either "tar", "tar.gz" or
+	// "tar.bz2". An archive of Path can not be compressed with bzip2. If
+	// empty, "tar" is used.
+	Format string
+
+	// Path is a local directory, which is archived while it is uploaded.
+	// Only regular files are archived and their modification times are set
+	// as the Mtime metadata.
+	Path string
+
+	// Progress is called, when a part of the archive has been uploaded.
+	Progress ProgressFunc
+}
+
+// extractArchiveResponse represents the response of the extract-archive
+// middleware.
+type extractArchiveResponse struct {
+	NumberFilesCreated int        `json:"Number Files Created"`
+	ResponseBody       string     `json:"Response Body"`
+	ResponseStatus     string     `json:"Response Status"`
+	Errors             [][]string `json:"Errors"`
+}
+
+// UploadArchive uploads an archive, which is extracted by the
+// extract-archive middleware. The files of the archive are created as
+// objects of the container, which may contain a pseudo-folder, e.g.
+// "container/folder". If the container name is empty, the top-level
+// directories of the archive are the containers of the objects. Missing
+// containers are created.
+//
+// The files, which could not be created, are reported by the Errors of
+// the result. An error is returned, when the archive could not be
+// extracted at all.
+//
+// https://docs.openstack.org/swift/latest/middleware.html#module-swift.common.middleware.bulk
+func UploadArchive(ctx context.Context, client *gophercloud.ServiceClient, containerName string, opts *UploadArchiveOpts) (*UploadArchiveResult, error) {
+	containerName, pseudoFolder := ContainerPartition(containerName)
+
+	format := opts.Format
+	if format == "" {
+		format = "tar"
+	}
+
+	switch format {
+	case "tar", "tar.gz":
+	case "tar.bz2":
+		if opts.Path != "" {
+			return nil, fmt.Errorf("unable to compress %s with bzip2", opts.Path)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive format %s", format)
+	}
+
+	if (opts.Content == nil) == (opts.Path == "") {
+		return nil, fmt.Errorf("either Content or Path must be specified")
+	}
+
+	uploadArchiveResult := &UploadArchiveResult{
+		Action:    "upload_archive",
+		Container: containerName,
+		Path:      opts.Path,
+	}
+
+	content := opts.Content
+	var pr *io.PipeReader
+	var writeErr chan error
+	if opts.Path != "" {
+		var pw *io.PipeWriter
+		pr, pw = io.Pipe()
+		writeErr = make(chan error, 1)
+
+		go func() {
+			err := writeArchive(pw, opts.Path, format == "tar.gz")
+			pw.CloseWithError(err)
+			writeErr <- err
+		}()
+
+		content = pr
+	}
+
+	tracker := newTransferTracker(containerName, "", -1, opts.Progress, opts.BandwidthLimiter)
+	content = tracker.reader(ctx, content, -1)
+
+	uploadURL := client.ResourceBaseURL()
+	if containerName != "" {
+		uploadURL += url.PathEscape(containerName)
+		if pseudoFolder != "" {
+			uploadURL += "/" + escapeObjectName(pseudoFolder)
+		}
+	}
+	uploadURL += "?extract-archive=" + format
+
+	var resp extractArchiveResponse
+	_, err := client.Put(ctx, uploadURL, content, &resp, &gophercloud.RequestOpts{
+		MoreHeaders: map[string]string{
+			"Accept": "application/json",
+		},
+		OkCodes: []int{http.StatusOK, http.StatusCreated},
+	})
+
+	if pr != nil {
+		// stop writing the archive, when the upload has failed
+		pr.Close()
+		// A closed pipe is the result of the failed upload.
+		werr := <-writeErr
+		if werr != nil && (err == nil || !errors.Is(werr, io.ErrClosedPipe)) {
+			return nil, fmt.Errorf("error archiving %s: %s", opts.Path, werr)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error uploading archive to %s: %s", containerName, err)
+	}
+
+	uploadArchiveResult.Created = resp.NumberFilesCreated
+	uploadArchiveResult.Status = resp.ResponseStatus
+
+	// The paths of the errors may include the version and the account.
+	u, err := url.Parse(client.ResourceBaseURL())
+	if err != nil {
+		return nil, err
+	}
+	accountPath := strings.TrimSuffix(u.Path, "/")
+
+	for _, e := range resp.Errors {
+		if len(e) == 0 {
+			continue
+		}
+
+		p, err := url.PathUnescape(e[0])
+		if err != nil {
+			p = e[0]
+		}
+		p = strings.TrimPrefix(strings.TrimPrefix(p, accountPath), "/")
+		eContainer, eObject, _ := strings.Cut(p, "/")
+
+		uploadResult := UploadResult{
+			Action:    "upload_object",
+			Container: eContainer,
+			Object:    eObject,
+		}
+		if len(e) > 1 {
+			uploadResult.Status = e[1]
+		}
+
+		uploadArchiveResult.Errors = append(uploadArchiveResult.Errors, uploadResult)
+	}
+
+	if !strings.HasPrefix(resp.ResponseStatus, "2") && len(uploadArchiveResult.Errors) == 0 {
+		return nil, fmt.Errorf("error extracting archive to %s: %s: %s", containerName, resp.ResponseStatus, strings.TrimSpace(resp.ResponseBody))
+	}
+
+	uploadArchiveResult.Success = len(uploadArchiveResult.Errors) == 0
+	return uploadArchiveResult, nil
+}
+
+// writeArchive writes a tar archive of the regular files of a directory.
+// The modification times are stored as the metadata used by the
+// extract-archive middleware.
+func writeArchive(w io.Writer, dir string, compress bool) error {
+	if compress {
+		gw := gzip.NewWriter(w)
+		if err := writeArchive(gw, dir, false); err != nil {
+			return err
+		}
+		return gw.Close()
+	}
+
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(name),
+			Size:     info.Size(),
+			Mode:     int64(info.Mode().Perm()),
+			ModTime:  info.ModTime(),
+			Format:   tar.FormatPAX,
+			PAXRecords: map[string]string{
+				"SCHILY.xattr.user.meta.mtime": formatMtime(info.ModTime()),
+			},
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.CopyN(tw, f, info.Size())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
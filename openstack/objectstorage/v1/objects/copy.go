@@ -0,0 +1,409 @@
+package objects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+)
+
+// CopyOpts represents options used for copying and moving objects.
+type CopyOpts struct {
+	// ContentType overrides the content type of the object.
+	ContentType string
+
+	// FreshMetadata will drop the metadata of the source object instead of
+	// preserving it.
+	FreshMetadata bool
+
+	// Metadata is added to the metadata of the object. It overrides the
+	// preserved metadata of the source object with the same keys.
+	Metadata map[string]string
+
+	// SegmentContainer is the container of the copied segments of a large
+	// object. If empty, "containerName_segments" is used.
+	SegmentContainer string
+
+	// ShareSegments will cause the copy of a large object within the same
+	// account to refer to the segments of its source instead of copies of
+	// them. Deleting either object with Delete then deletes the segments
+	// of the other one as well, unless DeleteOpts.LeaveSegments is set.
+	ShareSegments bool
+
+	// SourceAccount is the account of the source object, e.g. "AUTH_test",
+	// when it differs from the account of the client.
+	SourceAccount string
+}
+
+// copyFromOpts adds the headers of a server-side copy, which are not
+// supported by objects.CreateOpts.
+type copyFromOpts struct {
+	objects.CreateOpts
+	FreshMetadata bool
+	SourceAccount string
+}
+
+func (opts copyFromOpts) ToObjectCreateParams() (io.Reader, map[string]string, string, error) {
+	content, h, q, err := opts.CreateOpts.ToObjectCreateParams()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if opts.FreshMetadata {
+		h["X-Fresh-Metadata"] = "true"
+	}
+	if opts.SourceAccount != "" {
+		h["X-Copy-From-Account"] = opts.SourceAccount
+	}
+
+	return content, h, q, nil
+}
+
+// Copy copies an object server-side. The destination container is created,
+// if it does not exist.
+//
+// The segments of a static or dynamic large object are copied along with
+// its manifest, unless ShareSegments is set. A dynamic large object from
+// another account is copied as a single object.
+func Copy(ctx context.Context, client *gophercloud.ServiceClient, sourceContainer, sourceObject, containerName, objectName string, opts *CopyOpts) (*CopyResult, error) {
+	result, _, err := copyObject(ctx, client, sourceContainer, sourceObject, containerName, objectName, opts, "copy_object")
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Move moves an object server-side. The source object is copied like by
+// Copy and deleted only after the copy has been verified. The segments of a
+// large object are kept, when they are shared with the copy.
+func Move(ctx context.Context, client *gophercloud.ServiceClient, sourceContainer, sourceObject, containerName, objectName string, opts *CopyOpts) (*CopyResult, error) {
+	if opts.SourceAccount == "" && sourceContainer == containerName && sourceObject == objectName {
+		return nil, fmt.Errorf("unable to move object %s/%s onto itself", sourceContainer, sourceObject)
+	}
+
+	// The segments are not copied, because the source object is deleted.
+	moveOpts := *opts
+	moveOpts.ShareSegments = true
+
+	result, sharedSegments, err := copyObject(ctx, client, sourceContainer, sourceObject, containerName, objectName, &moveOpts, "move_object")
+	if err != nil {
+		return nil, err
+	}
+
+	sourceClient, err := accountClient(client, opts.SourceAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteOpts := &DeleteOpts{
+		LeaveSegments: sharedSegments,
+	}
+
+	deleteResults, err := Delete(ctx, sourceClient, sourceContainer, []string{sourceObject}, deleteOpts)
+	for _, deleteResult := range deleteResults {
+		if !deleteResult.Success {
+			return nil, fmt.Errorf("error deleting object %s/%s: %s", deleteResult.Container, deleteResult.Object, deleteResult.Status)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error deleting object %s/%s: %s", sourceContainer, sourceObject, err)
+	}
+
+	return result, nil
+}
+
+// copyObject copies an object and verifies the copy. It returns whether
+// the copy shares the segments of the source object.
+func copyObject(ctx context.Context, client *gophercloud.ServiceClient, sourceContainer, sourceObject, containerName, objectName string, opts *CopyOpts, action string) (*CopyResult, bool, error) {
+	result := &CopyResult{
+		Action:          action,
+		Container:       containerName,
+		Object:          objectName,
+		SourceContainer: sourceContainer,
+		SourceObject:    sourceObject,
+	}
+
+	sourceClient, err := accountClient(client, opts.SourceAccount)
+	if err != nil {
+		return nil, false, err
+	}
+
+	res := objects.Get(ctx, sourceClient, sourceContainer, sourceObject, nil)
+	sourceHeaders, err := res.Extract()
+	if err != nil {
+		return nil, false, fmt.Errorf("error retrieving object %s/%s: %s", sourceContainer, sourceObject, err)
+	}
+
+	sourceMetadata, err := res.ExtractMetadata()
+	if err != nil {
+		return nil, false, fmt.Errorf("error retrieving metadata of object %s/%s: %s", sourceContainer, sourceObject, err)
+	}
+
+	policy, err := createContainer(ctx, client, containerName, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating container %s: %s", containerName, err)
+	}
+
+	// Manifests are created by a regular PUT, so that the metadata of the
+	// source object has to be set explicitly.
+	metadata := make(map[string]string)
+	if !opts.FreshMetadata {
+		maps.Copy(metadata, sourceMetadata)
+	}
+	maps.Copy(metadata, opts.Metadata)
+
+	createOpts := objects.CreateOpts{
+		Content:     strings.NewReader(""),
+		ContentType: opts.ContentType,
+		Metadata:    metadata,
+	}
+	if createOpts.ContentType == "" {
+		createOpts.ContentType = sourceHeaders.ContentType
+	}
+
+	segmentContainer := opts.SegmentContainer
+	if segmentContainer == "" {
+		segmentContainer = containerName + "_segments"
+	}
+
+	var sharedSegments, verifyETag bool
+	switch {
+	case sourceHeaders.StaticLargeObject && opts.SourceAccount == "" && opts.ShareSegments:
+		result.LargeObject = true
+		sharedSegments, verifyETag = true, true
+		err = copySLOManifest(ctx, client, sourceContainer, sourceObject, containerName, objectName, createOpts)
+	case sourceHeaders.StaticLargeObject:
+		// Nested static large objects are flattened, which changes the etag.
+		result.LargeObject = true
+		if _, err := createContainer(ctx, client, segmentContainer, policy); err != nil {
+			return nil, false, fmt.Errorf("error creating segment container %s: %s", segmentContainer, err)
+		}
+		segmentPrefix := fmt.Sprintf("%s/slo/%s/%d/", objectName, formatMtime(sourceHeaders.LastModified), sourceHeaders.ContentLength)
+		err = copySLOSegments(ctx, client, sourceClient, opts.SourceAccount, sourceContainer, sourceObject, segmentContainer, segmentPrefix, containerName, objectName, createOpts)
+	case sourceHeaders.ObjectManifest != "" && opts.SourceAccount == "" && opts.ShareSegments:
+		result.LargeObject = true
+		sharedSegments, verifyETag = true, true
+		createOpts.ObjectManifest = sourceHeaders.ObjectManifest
+		err = objects.Create(ctx, client, containerName, objectName, createOpts).Err
+	case sourceHeaders.ObjectManifest != "" && opts.SourceAccount == "":
+		result.LargeObject = true
+		verifyETag = true
+		if _, err := createContainer(ctx, client, segmentContainer, policy); err != nil {
+			return nil, false, fmt.Errorf("error creating segment container %s: %s", segmentContainer, err)
+		}
+		segmentPrefix := fmt.Sprintf("%s/dlo/%s/%d/", objectName, formatMtime(sourceHeaders.LastModified), sourceHeaders.ContentLength)
+		err = copyDLOSegments(ctx, client, sourceContainer, sourceObject, sourceHeaders.ObjectManifest, segmentContainer, segmentPrefix, containerName, objectName, createOpts)
+	default:
+		// The data of a dynamic large object is concatenated, so that its
+		// etag changes.
+		verifyETag = sourceHeaders.ObjectManifest == ""
+		copyOpts := copyFromOpts{
+			CreateOpts: objects.CreateOpts{
+				Content:     strings.NewReader(""),
+				ContentType: opts.ContentType,
+				CopyFrom:    "/" + url.PathEscape(sourceContainer) + "/" + escapeObjectName(sourceObject),
+				Metadata:    opts.Metadata,
+				NoETag:      true,
+			},
+			FreshMetadata: opts.FreshMetadata,
+			SourceAccount: opts.SourceAccount,
+		}
+		err = objects.Create(ctx, client, containerName, objectName, copyOpts).Err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error copying object %s/%s to %s/%s: %s", sourceContainer, sourceObject, containerName, objectName, err)
+	}
+
+	headers, err := objects.Get(ctx, client, containerName, objectName, nil).Extract()
+	if err != nil {
+		return nil, false, fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, err)
+	}
+
+	if headers.ContentLength != sourceHeaders.ContentLength || (verifyETag && strings.Trim(headers.ETag, `"`) != strings.Trim(sourceHeaders.ETag, `"`)) {
+		return nil, false, fmt.Errorf("object %s/%s does not match its source %s/%s", containerName, objectName, sourceContainer, sourceObject)
+	}
+
+	result.Success = true
+	return result, sharedSegments, nil
+}
+
+// copySLOManifest copies the manifest of a static large object within the
+// same account.
+func copySLOManifest(ctx context.Context, client *gophercloud.ServiceClient, sourceContainer, sourceObject, containerName, objectName string, createOpts objects.CreateOpts) error {
+	downloadOpts := objects.DownloadOpts{
+		MultipartManifest: "get",
+	}
+
+	res := objects.Download(ctx, client, sourceContainer, sourceObject, downloadOpts)
+	if res.Err != nil {
+		return res.Err
+	}
+
+	body, err := res.ExtractContent()
+	if err != nil {
+		return err
+	}
+
+	multipartManifest, err := ExtractMultipartManifest(body)
+	if err != nil {
+		return err
+	}
+
+	manifest := make([]sloManifest, len(multipartManifest))
+	for i, m := range multipartManifest {
+		manifest[i] = sloManifest{
+			Path:      m.Name,
+			ETag:      m.Hash,
+			SizeBytes: m.Bytes,
+			Range:     m.Range,
+		}
+	}
+
+	return putSLOManifest(ctx, client, containerName, objectName, manifest, createOpts)
+}
+
+// copySLOSegments copies the segments of a static large object from another
+// account and creates a manifest of the copied segments.
+func copySLOSegments(ctx context.Context, client, sourceClient *gophercloud.ServiceClient, sourceAccount, sourceContainer, sourceObject, segmentContainer, segmentPrefix, containerName, objectName string, createOpts objects.CreateOpts) error {
+	segments, err := getSLOManifest(ctx, sourceClient, sourceContainer, sourceObject, 1, nil)
+	if err != nil {
+		return fmt.Errorf("unable to get manifest: %s", err)
+	}
+
+	// A segment may be referenced several times by nested static large
+	// objects.
+	copied := make(map[string]sloManifest)
+
+	manifest := make([]sloManifest, 0, len(segments))
+	for _, segment := range segments {
+		m, ok := copied[segment.Name]
+		if !ok {
+			sContainer, sObject, ok := strings.Cut(strings.TrimPrefix(segment.Name, "/"), "/")
+			if !ok {
+				return fmt.Errorf("unable to parse segment name %s", segment.Name)
+			}
+
+			// The size of a ranged segment is the size of the range.
+			size := segment.Bytes
+			if segment.Range != "" {
+				headers, err := objects.Get(ctx, sourceClient, sContainer, sObject, nil).Extract()
+				if err != nil {
+					return fmt.Errorf("error retrieving segment %s: %s", segment.Name, err)
+				}
+				size = headers.ContentLength
+			}
+
+			segmentName := fmt.Sprintf("%s%08d", segmentPrefix, len(copied))
+			copyOpts := copyFromOpts{
+				CreateOpts: objects.CreateOpts{
+					Content:  strings.NewReader(""),
+					CopyFrom: "/" + url.PathEscape(sContainer) + "/" + escapeObjectName(sObject),
+					NoETag:   true,
+				},
+				SourceAccount: sourceAccount,
+			}
+
+			if err := objects.Create(ctx, client, segmentContainer, segmentName, copyOpts).Err; err != nil {
+				return fmt.Errorf("error copying segment %s: %s", segment.Name, err)
+			}
+
+			m = sloManifest{
+				Path:      "/" + segmentContainer + "/" + segmentName,
+				ETag:      segment.Hash,
+				SizeBytes: size,
+			}
+			copied[segment.Name] = m
+		}
+
+		m.Range = segment.Range
+		manifest = append(manifest, m)
+	}
+
+	return putSLOManifest(ctx, client, containerName, objectName, manifest, createOpts)
+}
+
+// copyDLOSegments copies the segments of a dynamic large object within the
+// same account and creates a manifest of the copied segments.
+func copyDLOSegments(ctx context.Context, client *gophercloud.ServiceClient, sourceContainer, sourceObject, objectManifest, segmentContainer, segmentPrefix, containerName, objectName string, createOpts objects.CreateOpts) error {
+	mo := GetManifestOpts{
+		ContainerName:  sourceContainer,
+		ObjectManifest: objectManifest,
+		ObjectName:     sourceObject,
+	}
+
+	segments, err := GetManifest(ctx, client, mo)
+	if err != nil {
+		return fmt.Errorf("unable to get manifest: %s", err)
+	}
+
+	sContainer, _, err := parseObjectManifest(objectManifest)
+	if err != nil {
+		return err
+	}
+
+	for i, segment := range segments {
+		segmentName := fmt.Sprintf("%s%08d", segmentPrefix, i)
+		copyOpts := objects.CreateOpts{
+			Content:  strings.NewReader(""),
+			CopyFrom: "/" + url.PathEscape(sContainer) + "/" + escapeObjectName(segment.Name),
+			NoETag:   true,
+		}
+
+		if err := objects.Create(ctx, client, segmentContainer, segmentName, copyOpts).Err; err != nil {
+			return fmt.Errorf("error copying segment %s/%s: %s", sContainer, segment.Name, err)
+		}
+	}
+
+	createOpts.ObjectManifest = formatObjectManifest(segmentContainer, segmentPrefix)
+
+	return objects.Create(ctx, client, containerName, objectName, createOpts).Err
+}
+
+// putSLOManifest creates a static large object of the segments.
+func putSLOManifest(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, manifest []sloManifest, createOpts objects.CreateOpts) error {
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	createOpts.Content = strings.NewReader(string(b))
+	createOpts.MultipartManifest = "put"
+	createOpts.NoETag = true
+
+	return objects.Create(ctx, client, containerName, objectName, createOpts).Err
+}
+
+// accountClient returns a copy of the client for another account of the
+// same object storage. The account is the last element of the endpoint
+// path, e.g. "AUTH_test" of "https://swift.example.com/v1/AUTH_test/".
+func accountClient(client *gophercloud.ServiceClient, account string) (*gophercloud.ServiceClient, error) {
+	if account == "" {
+		return client, nil
+	}
+
+	u, err := url.Parse(client.ResourceBaseURL())
+	if err != nil {
+		return nil, err
+	}
+
+	p := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(p, "/")
+	if i < 0 || p[i+1:] == "" {
+		return nil, fmt.Errorf("unable to determine the account of endpoint %s", client.ResourceBaseURL())
+	}
+
+	u.Path = p[:i+1] + account + "/"
+	u.RawPath = ""
+
+	c := *client
+	c.ResourceBase = u.String()
+
+	return &c, nil
+}
@@ -0,0 +1,355 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/containers"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+)
+
+// DeleteOpts represents options used for deleting objects.
+type DeleteOpts struct {
+	// Concurrency is the number of objects deleted in parallel, when the
+	// bulk-delete middleware is not available. If zero, objects are deleted
+	// sequentially.
+	Concurrency int
+
+	// DeleteContainer will delete the container once all of its objects
+	// have been deleted. It is only used, when no object names are
+	// specified.
+	DeleteContainer bool
+
+	// LeaveSegments will cause the segments of large objects to be left in
+	// their containers. It has to be set, when the segments are shared with
+	// other large objects, e.g. copies created with CopyOpts.ShareSegments,
+	// which would become unreadable otherwise.
+	LeaveSegments bool
+
+	// NoBulkDelete will prevent the use of the bulk-delete middleware.
+	NoBulkDelete bool
+
+	// Prefix is a prefix of the objects to be deleted, when no object names
+	// are specified.
+	Prefix string
+}
+
+// swiftInfo represents the capabilities of a Swift cluster used by Delete.
+type swiftInfo struct {
+	BulkDelete *struct {
+		MaxDeletesPerRequest int `json:"max_deletes_per_request"`
+	} `json:"bulk_delete"`
+}
+
+// Delete deletes objects from a container. The segments of static and
+// dynamic large objects are deleted as well, unless LeaveSegments is set.
+// If no object names are specified, all objects of the container are
+// deleted. The bulk-delete middleware is used, when it is available.
+//
+// https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L2129
+func Delete(ctx context.Context, client *gophercloud.ServiceClient, containerName string, objectNames []string, opts *DeleteOpts) ([]DeleteResult, error) {
+	containerName, pseudoFolder := ContainerPartition(containerName)
+
+	deleteContainer := opts.DeleteContainer && len(objectNames) == 0 && opts.Prefix == "" && pseudoFolder == ""
+
+	if len(objectNames) == 0 {
+		prefix := opts.Prefix
+		if pseudoFolder != "" {
+			prefix = pseudoFolder + "/" + prefix
+		}
+
+		allObjects, err := listObjects(ctx, client, containerName, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("error listing container %s: %s", containerName, err)
+		}
+
+		for _, object := range allObjects {
+			objectNames = append(objectNames, object.Name)
+		}
+	} else if pseudoFolder != "" {
+		names := make([]string, len(objectNames))
+		for i, objectName := range objectNames {
+			names[i] = pseudoFolder + "/" + objectName
+		}
+		objectNames = names
+	}
+
+	// segments maps the segment containers to the segments to be deleted.
+	segments := make(map[string][]string)
+	if !opts.LeaveSegments {
+		var mu sync.Mutex
+		err := forEachConcurrently(ctx, opts.Concurrency, len(objectNames), func(ctx context.Context, i int) error {
+			objectSegments, err := getSegments(ctx, client, containerName, objectNames[i])
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			for segmentContainer, segmentNames := range objectSegments {
+				segments[segmentContainer] = append(segments[segmentContainer], segmentNames...)
+			}
+			mu.Unlock()
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bulkLimit := 0
+	if !opts.NoBulkDelete {
+		bulkLimit = getBulkDeleteLimit(ctx, client)
+	}
+
+	results, err := deleteObjects(ctx, client, containerName, objectNames, "delete_object", bulkLimit, opts.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	for segmentContainer, segmentNames := range segments {
+		if len(segmentNames) == 0 {
+			continue
+		}
+
+		segmentResults, err := deleteObjects(ctx, client, segmentContainer, segmentNames, "delete_segment", bulkLimit, opts.Concurrency)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, segmentResults...)
+	}
+
+	var failed int
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("error deleting %d objects of container %s", failed, containerName)
+	}
+
+	if deleteContainer {
+		res := containers.Delete(ctx, client, containerName)
+		if res.Err != nil && !gophercloud.ResponseCodeIs(res.Err, http.StatusNotFound) {
+			return results, fmt.Errorf("error deleting container %s: %s", containerName, res.Err)
+		}
+
+		results = append(results, DeleteResult{
+			Action:    "delete_container",
+			Container: containerName,
+			Success:   true,
+		})
+	}
+
+	return results, nil
+}
+
+// getSegments returns the segments of a large object mapped by their
+// containers. The manifests of nested static large objects are returned as
+// segments as well. It returns no segments, if the object is not a large
+// object or does not exist.
+func getSegments(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string) (map[string][]string, error) {
+	headers, err := objects.Get(ctx, client, containerName, objectName, nil).Extract()
+	if err != nil {
+		if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, err)
+	}
+
+	segments := make(map[string][]string)
+
+	// The segments of a dynamic large object are listed by their names in
+	// the segment container.
+	if headers.ObjectManifest != "" {
+		mo := GetManifestOpts{
+			ContainerName:  containerName,
+			ObjectManifest: headers.ObjectManifest,
+			ObjectName:     objectName,
+		}
+
+		manifestData, err := GetManifest(ctx, client, mo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get manifest for %s/%s: %s", containerName, objectName, err)
+		}
+
+		segmentContainer, _, err := parseObjectManifest(headers.ObjectManifest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range manifestData {
+			segments[segmentContainer] = append(segments[segmentContainer], data.Name)
+		}
+
+		return segments, nil
+	}
+
+	if !headers.StaticLargeObject {
+		return nil, nil
+	}
+
+	// The same segment may be referenced multiple times with different
+	// ranges.
+	seen := make(map[string]bool)
+	addSegment := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		sContainer, sObject, ok := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+		if ok {
+			segments[sContainer] = append(segments[sContainer], sObject)
+		}
+	}
+
+	manifestData, err := getSLOManifest(ctx, client, containerName, objectName, 1, addSegment)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get manifest for %s/%s: %s", containerName, objectName, err)
+	}
+
+	for _, data := range manifestData {
+		addSegment(data.Name)
+	}
+
+	return segments, nil
+}
+
+// getBulkDeleteLimit returns the maximum number of objects deleted by a bulk
+// delete request, or zero, if the bulk-delete middleware is not available.
+func getBulkDeleteLimit(ctx context.Context, client *gophercloud.ServiceClient) int {
+	u, err := url.Parse(client.ResourceBaseURL())
+	if err != nil {
+		return 0
+	}
+	infoURL := u.Scheme + "://" + u.Host + "/info"
+
+	var info swiftInfo
+	_, err = client.Get(ctx, infoURL, &info, &gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusOK},
+	})
+	if err != nil || info.BulkDelete == nil {
+		return 0
+	}
+
+	if info.BulkDelete.MaxDeletesPerRequest <= 0 {
+		return 10000
+	}
+
+	return info.BulkDelete.MaxDeletesPerRequest
+}
+
+// deleteObjects deletes objects of a container using bulk delete requests
+// of up to bulkLimit objects, or concurrent delete requests, if bulkLimit is
+// zero or the bulk-delete middleware is not supported. Objects, which do not
+// exist, are considered deleted. The status of a failed deletion is set in
+// its result.
+func deleteObjects(ctx context.Context, client *gophercloud.ServiceClient, containerName string, objectNames []string, action string, bulkLimit, concurrency int) ([]DeleteResult, error) {
+	results := make([]DeleteResult, len(objectNames))
+	for i, objectName := range objectNames {
+		results[i] = DeleteResult{
+			Action:    action,
+			Container: containerName,
+			Object:    objectName,
+		}
+	}
+
+	var pending []int
+	if bulkLimit <= 0 {
+		for i := range objectNames {
+			pending = append(pending, i)
+		}
+	}
+
+	for start := 0; bulkLimit > 0 && start < len(objectNames); start += bulkLimit {
+		end := min(start+bulkLimit, len(objectNames))
+
+		resp, err := objects.BulkDelete(ctx, client, containerName, objectNames[start:end]).Extract()
+		if err != nil {
+			if gophercloud.ResponseCodeIs(err, http.StatusNotFound) || gophercloud.ResponseCodeIs(err, http.StatusNotImplemented) {
+				// fall back to deleting the objects one by one
+				for i := start; i < end; i++ {
+					pending = append(pending, i)
+				}
+				continue
+			}
+
+			for i := start; i < end; i++ {
+				results[i].Error = err
+				results[i].Status = errorStatus(err)
+			}
+			continue
+		}
+
+		failed := make(map[string]string, len(resp.Errors))
+		for _, e := range resp.Errors {
+			if len(e) == 0 {
+				continue
+			}
+			if v, err := url.PathUnescape(e[0]); err == nil {
+				failed[v] = resp.ResponseStatus
+				if len(e) > 1 {
+					failed[v] = e[1]
+				}
+			}
+		}
+
+		// A failed request without errors of individual objects fails all
+		// of them.
+		requestFailed := len(failed) == 0 && resp.ResponseStatus != "" && !strings.HasPrefix(resp.ResponseStatus, "2")
+
+		for i := start; i < end; i++ {
+			status, ok := failed["/"+containerName+"/"+objectNames[i]]
+			switch {
+			case ok:
+				results[i].Status = status
+			case requestFailed:
+				results[i].Status = strings.TrimSpace(resp.ResponseStatus + " " + resp.ResponseBody)
+			default:
+				results[i].Success = true
+				continue
+			}
+			results[i].Error = fmt.Errorf("error deleting object %s/%s: %s", containerName, objectNames[i], results[i].Status)
+		}
+	}
+
+	err := forEachConcurrently(ctx, concurrency, len(pending), func(ctx context.Context, i int) error {
+		result := &results[pending[i]]
+
+		res := objects.Delete(ctx, client, containerName, result.Object, nil)
+		if res.Err != nil && !gophercloud.ResponseCodeIs(res.Err, http.StatusNotFound) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			result.Error = res.Err
+			result.Status = errorStatus(res.Err)
+			return nil
+		}
+
+		result.Success = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// errorStatus returns the HTTP status of a failed request, e.g.
+// "409 Conflict", or the error message, if there was no response.
+func errorStatus(err error) string {
+	var e gophercloud.ErrUnexpectedResponseCode
+	if errors.As(err, &e) {
+		return fmt.Sprintf("%d %s", e.Actual, http.StatusText(e.Actual))
+	}
+	return err.Error()
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
@@ -17,11 +19,31 @@ import (
 	"github.com/gophercloud/gophercloud/v2/pagination"
 )
 
+// DefaultRangeSize is the default size of the byte ranges of an object
+// downloaded in parallel.
+const DefaultRangeSize = 64 * 1024 * 1024
+
 // DownloadOpts represents options used for downloading an object.
 type DownloadOpts struct {
+	// BandwidthLimiter limits the bandwidth of the downloads.
+	BandwidthLimiter *BandwidthLimiter
+
+	// Checksum will verify the md5sum of a downloaded file against the etag
+	// of the object or the etags of its segments. Large objects with ranged
+	// segments cannot be verified.
+	Checksum bool
+
 	// Delimiter is a delimiter to specify for listing objects.
 	Delimiter string
 
+	// IfMatch is the etag, which a resumed object must match. It should be
+	// the etag of the object, when the partial file was downloaded, as
+	// returned by DownloadResult.ETag. If empty, the etag stored next to
+	// the partial file is used. A partial file without a stored etag, or
+	// with the etag of another version of the object, is downloaded again
+	// from the start.
+	IfMatch string
+
 	// IgnoreMtime won't update the downloaded file's mtime.
 	IgnoreMtime bool
 
@@ -37,9 +59,28 @@ type DownloadOpts struct {
 	// Prefix is a prefix string for a container.
 	Prefix string
 
+	// Progress is called, when a part of an object has been downloaded.
+	Progress ProgressFunc
+
+	// RangeConcurrency is the number of byte ranges of an object downloaded
+	// in parallel. If greater than one, objects larger than RangeSize are
+	// downloaded using concurrent range requests. When such a download
+	// fails, only the contiguous downloaded part at the start of the file
+	// is kept, so that ranges completed after the failed one are downloaded
+	// again by a resumed download.
+	RangeConcurrency int
+
+	// RangeSize is the size of the byte ranges downloaded in parallel. If
+	// zero, DefaultRangeSize is used.
+	RangeSize int64
+
 	// RemovePrefix will remove the prefix from the container.
 	RemovePrefix bool
 
+	// Resume will continue the download of a partial file using a range
+	// request, instead of downloading the whole object again.
+	Resume bool
+
 	// SkipIdentical will skip identical objects already downloaded.
 	SkipIdentical bool
 
@@ -73,7 +114,7 @@ func Download(ctx context.Context, client *gophercloud.ServiceClient, containerN
 				}
 
 				for _, c := range containerList {
-					results, err := downloadContainer(ctx, client, c.Name, opts)
+					results, err := downloadContainer(ctx, client, c.Name, opts, 0, nil)
 					if err != nil {
 						return false, fmt.Errorf("error downloading container %s: %s", c.Name, err)
 					}
@@ -93,7 +134,7 @@ func Download(ctx context.Context, client *gophercloud.ServiceClient, containerN
 	}
 
 	if len(objectNames) == 0 {
-		results, err := downloadContainer(ctx, client, containerName, opts)
+		results, err := downloadContainer(ctx, client, containerName, opts, 0, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error downloading container %s: %s", containerName, err)
 		}
@@ -115,15 +156,21 @@ func Download(ctx context.Context, client *gophercloud.ServiceClient, containerN
 
 // downloadObject will download a specified object.
 func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, containerName string, objectName string, opts *DownloadOpts) (*DownloadResult, error) {
-	var objectDownloadOpts objects.DownloadOpts
-	var pseudoDir bool
-
 	// Perform a get on the object in order to get its metadata.
 	originalObject := objects.Get(ctx, client, containerName, objectName, nil)
 	if originalObject.Err != nil {
 		return nil, fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, originalObject.Err)
 	}
 
+	return downloadRetrievedObject(ctx, client, containerName, objectName, originalObject, opts)
+}
+
+// downloadRetrievedObject downloads an object, whose headers and metadata
+// have already been retrieved.
+func downloadRetrievedObject(ctx context.Context, client *gophercloud.ServiceClient, containerName string, objectName string, originalObject objects.GetResult, opts *DownloadOpts) (*DownloadResult, error) {
+	var objectDownloadOpts objects.DownloadOpts
+	var pseudoDir bool
+
 	originalMetadata, err := originalObject.ExtractMetadata()
 	if err != nil {
 		return nil, fmt.Errorf("error extracting object metadata for %s/%s: %s", containerName, objectName, err)
@@ -165,19 +212,54 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 		}
 	}
 
-	// Attempt to download the object
-	res := objects.Download(ctx, client, containerName, objectName, objectDownloadOpts)
-	if res.Err != nil {
-		// Ignore the error if SkipIdentical is set.
-		// This is because a second attempt to download the object will happen later.
-		if !opts.SkipIdentical {
-			return nil, fmt.Errorf("error getting object %s/%s: %s", containerName, objectName, res.Err)
+	// Resumed and ranged downloads retrieve the content using range
+	// requests, so that the headers of the object are sufficient.
+	ranged := (opts.Resume || opts.RangeConcurrency > 1) && !opts.NoDownload && opts.OutFile != "-"
+
+	var res objects.DownloadResult
+	headers := new(objects.DownloadHeader)
+	if ranged {
+		if err := originalObject.ExtractInto(headers); err != nil {
+			return nil, fmt.Errorf("error extracting headers from %s: %s", objectName, err)
+		}
+	} else {
+		// Attempt to download the object
+		res = objects.Download(ctx, client, containerName, objectName, objectDownloadOpts)
+		if res.Err != nil {
+			// Ignore the error if SkipIdentical is set.
+			// This is because a second attempt to download the object will happen later.
+			if !opts.SkipIdentical {
+				return nil, fmt.Errorf("error getting object %s/%s: %s", containerName, objectName, res.Err)
+			}
+		}
+
+		headers, err = res.Extract()
+		if err != nil {
+			return nil, fmt.Errorf("error extracting headers from %s: %s", objectName, err)
 		}
 	}
 
-	headers, err := res.Extract()
-	if err != nil {
-		return nil, fmt.Errorf("error extracting headers from %s: %s", objectName, err)
+	// The local file is identical, when the If-None-Match header of the
+	// download matches. Ranged downloads compare the etag of the object,
+	// because they do not send the header. Either way the file must not be
+	// touched.
+	if opts.SkipIdentical && objectDownloadOpts.IfNoneMatch != "" && headers.ObjectManifest == "" && !headers.StaticLargeObject &&
+		objectDownloadOpts.IfNoneMatch == strings.Trim(headers.ETag, `"`) {
+		if res.Body != nil {
+			res.Body.Close()
+		}
+
+		downloadResult := &DownloadResult{
+			Action:    "download_object",
+			Container: containerName,
+			ETag:      strings.Trim(headers.ETag, `"`),
+			Object:    objectName,
+			Path:      objectPath,
+			PseudoDir: pseudoDir,
+			Success:   true,
+		}
+
+		return downloadResult, nil
 	}
 
 	if opts.SkipIdentical {
@@ -234,20 +316,31 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 				}
 
 				// This is a Large object
-				objectDownloadOpts.MultipartManifest = ""
-				res = objects.Download(ctx, client, containerName, objectName, objectDownloadOpts)
-				if res.Err != nil {
-					return nil, fmt.Errorf("error downloading object %s/%s: %s", containerName, objectName, err)
+				if !ranged {
+					objectDownloadOpts.MultipartManifest = ""
+					res = objects.Download(ctx, client, containerName, objectName, objectDownloadOpts)
+					if res.Err != nil {
+						return nil, fmt.Errorf("error downloading object %s/%s: %s", containerName, objectName, err)
+					}
 				}
 			}
 		}
 	}
 
+	// tracker reports the progress and limits the bandwidth of the download.
+	tracker := newTransferTracker(containerName, objectName, headers.ContentLength, opts.Progress, opts.BandwidthLimiter)
+
 	if opts.OutFile == "-" && !opts.NoDownload {
+		content := res.Body
+		if body := tracker.reader(ctx, res.Body, -1); body != res.Body {
+			content = &transferReadCloser{Reader: body, Closer: res.Body}
+		}
+
 		downloadResult := &DownloadResult{
 			Action:    "download_object",
 			Container: containerName,
-			Content:   res.Body,
+			Content:   content,
+			ETag:      strings.Trim(headers.ETag, `"`),
 			Object:    objectName,
 			Path:      objectPath,
 			PseudoDir: pseudoDir,
@@ -274,7 +367,8 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 		}
 	} else {
 		if !opts.NoDownload && opts.OutFile != "" {
-			dir := filepath.Dir(objectPath)
+			// The file is written to OutFile, not to the object path.
+			dir := filepath.Dir(filename)
 			if _, err := os.Stat(dir); err != nil && os.IsNotExist(err) {
 				if err := os.MkdirAll(dir, 0777); err != nil {
 					return nil, fmt.Errorf("error creating directory %s: %s", dir, err)
@@ -295,16 +389,21 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 			}
 		}
 
-		if file != "" {
+		if file != "" && ranged {
+			if err := downloadRanges(ctx, client, containerName, objectName, file, headers, opts, tracker); err != nil {
+				return nil, err
+			}
+		} else if file != "" {
 			f, err := os.Create(file)
 			if err != nil {
 				return nil, fmt.Errorf("error creating file %s: %s", file, err)
 			}
 			defer f.Close()
 
+			body := tracker.reader(ctx, res.Body, -1)
 			buf := make([]byte, diskBuffer)
 			for {
-				chunk, err := res.Body.Read(buf)
+				chunk, err := body.Read(buf)
 				if err != nil && err != io.EOF {
 					return nil, fmt.Errorf("error reading object %s/%s: %s", containerName, objectName, err)
 				}
@@ -320,6 +419,12 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 			f.Close()
 		}
 
+		if file != "" && opts.Checksum {
+			if err := verifyDownload(ctx, client, containerName, objectName, file, headers); err != nil {
+				return nil, err
+			}
+		}
+
 		if !opts.NoDownload && !opts.IgnoreMtime {
 			if v, ok := originalMetadata["Mtime"]; ok {
 				epoch, err := strconv.ParseInt(v, 10, 64)
@@ -338,6 +443,7 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 		Action:    "download_object",
 		Success:   true,
 		Container: containerName,
+		ETag:      strings.Trim(headers.ETag, `"`),
 		Object:    objectName,
 		Path:      objectPath,
 		PseudoDir: pseudoDir,
@@ -346,13 +452,224 @@ func downloadObject(ctx context.Context, client *gophercloud.ServiceClient, cont
 	return downloadResult, nil
 }
 
-// downloadContainer will download all objects in a given container.
-func downloadContainer(ctx context.Context, client *gophercloud.ServiceClient, containerName string, opts *DownloadOpts) ([]DownloadResult, error) {
+// byteRange represents a byte range of an object.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// etagFile returns the name of the file, which stores the etag of the
+// object downloaded to a partial file.
+func etagFile(file string) string {
+	dir, name := filepath.Split(file)
+	return filepath.Join(dir, "."+name+".etag")
+}
+
+// downloadRanges downloads an object into a file using range requests. A
+// partial file is resumed, if Resume is set. The ranges are downloaded in
+// parallel, if RangeConcurrency is greater than one. If the download fails,
+// the file is truncated to the contiguous downloaded part at its start, so
+// that it can be resumed. Completed ranges after a failed one are discarded.
+// The etag of the downloaded object is stored next to the file until the
+// download is complete.
+func downloadRanges(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName, file string, headers *objects.DownloadHeader, opts *DownloadOpts, tracker *transferTracker) error {
+	size := headers.ContentLength
+
+	eTag := opts.IfMatch
+	if eTag == "" {
+		eTag = strings.Trim(headers.ETag, `"`)
+	}
+
+	var offset int64
+	if opts.Resume {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() && info.Size() <= size {
+			offset = info.Size()
+		}
+
+		// A partial file is only resumed, when it is known to belong to
+		// the same version of the object.
+		if offset > 0 && opts.IfMatch == "" {
+			stored, err := os.ReadFile(etagFile(file))
+			if err != nil || strings.TrimSpace(string(stored)) != eTag {
+				offset = 0
+			}
+		}
+	}
+
+	if err := os.WriteFile(etagFile(file), []byte(eTag+"\n"), 0666); err != nil {
+		return fmt.Errorf("error storing etag of file %s: %s", file, err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(file, flags, 0666)
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %s", file, err)
+	}
+	defer f.Close()
+
+	rangeSize := size - offset
+	if opts.RangeConcurrency > 1 {
+		rangeSize = opts.RangeSize
+		if rangeSize <= 0 {
+			rangeSize = DefaultRangeSize
+		}
+	}
+
+	var ranges []byteRange
+	for start := offset; start < size; start += rangeSize {
+		ranges = append(ranges, byteRange{start: start, end: min(start+rangeSize, size)})
+	}
+
+	// The resumed part of the file counts as downloaded.
+	tracker.add(-1, offset, offset)
+
+	var mu sync.Mutex
+	done := make([]bool, len(ranges))
+	err = forEachConcurrently(ctx, opts.RangeConcurrency, len(ranges), func(ctx context.Context, i int) error {
+		r := ranges[i]
+
+		downloadOpts := objects.DownloadOpts{
+			IfMatch: eTag,
+			Range:   fmt.Sprintf("bytes=%d-%d", r.start, r.end-1),
+		}
+
+		res := objects.Download(ctx, client, containerName, objectName, downloadOpts)
+		if res.Err != nil {
+			if gophercloud.ResponseCodeIs(res.Err, http.StatusPreconditionFailed) {
+				return fmt.Errorf("object %s/%s has changed", containerName, objectName)
+			}
+			return fmt.Errorf("error downloading range %d-%d of object %s/%s: %s", r.start, r.end-1, containerName, objectName, res.Err)
+		}
+		defer res.Body.Close()
+
+		if r.start != 0 && res.Header.Get("Content-Range") == "" {
+			return fmt.Errorf("range requests are not supported for object %s/%s", containerName, objectName)
+		}
+
+		segment := i
+		if opts.RangeConcurrency <= 1 {
+			segment = -1
+		}
+
+		body := tracker.reader(ctx, io.LimitReader(res.Body, r.end-r.start), segment)
+		n, err := io.Copy(io.NewOffsetWriter(f, r.start), body)
+		if err != nil {
+			rollbackTransfer(body)
+			return fmt.Errorf("error downloading range %d-%d of object %s/%s: %s", r.start, r.end-1, containerName, objectName, err)
+		}
+		if n != r.end-r.start {
+			rollbackTransfer(body)
+			return fmt.Errorf("error downloading range %d-%d of object %s/%s: short read", r.start, r.end-1, containerName, objectName)
+		}
+
+		mu.Lock()
+		done[i] = true
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		// Keep the contiguous downloaded part only.
+		end := offset
+		for i, r := range ranges {
+			if !done[i] {
+				break
+			}
+			end = r.end
+		}
+		if truncErr := f.Truncate(end); truncErr != nil {
+			return fmt.Errorf("%s, error truncating file %s: %s", err, file, truncErr)
+		}
+
+		return err
+	}
+
+	// A resumed file may be longer than an object, which has changed.
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("error truncating file %s: %s", file, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(etagFile(file)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing etag of file %s: %s", file, err)
+	}
+
+	return nil
+}
+
+// verifyDownload compares a downloaded file to the etag of an object or the
+// etags of its segments.
+func verifyDownload(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName, file string, headers *objects.DownloadHeader) error {
+	if headers.StaticLargeObject || headers.ObjectManifest != "" {
+		mo := GetManifestOpts{
+			ContainerName:     containerName,
+			ContentLength:     headers.ContentLength,
+			ETag:              headers.ETag,
+			ObjectManifest:    headers.ObjectManifest,
+			ObjectName:        objectName,
+			StaticLargeObject: headers.StaticLargeObject,
+		}
+
+		manifestData, err := GetManifest(ctx, client, mo)
+		if err != nil {
+			return fmt.Errorf("unable to get manifest for %s/%s: %s", containerName, objectName, err)
+		}
+
+		for _, data := range manifestData {
+			if data.Range != "" {
+				return nil
+			}
+		}
+
+		ok, err := IsIdentical(manifestData, file)
+		if err != nil {
+			return fmt.Errorf("error comparing object %s/%s and path %s: %s", containerName, objectName, file, err)
+		}
+		if !ok {
+			return fmt.Errorf("download verification failed: segments of %s/%s do not match %s", containerName, objectName, file)
+		}
+
+		return nil
+	}
+
+	checksum, err := FileMD5Sum(file)
+	if err != nil {
+		return fmt.Errorf("error getting md5sum of file %s: %s", file, err)
+	}
+
+	eTag := strings.Trim(headers.ETag, `"`)
+	if checksum != eTag {
+		return fmt.Errorf("download verification failed: md5 mismatch, local %s != remote %s", checksum, eTag)
+	}
+
+	return nil
+}
+
+// downloadObjectFunc downloads an object of a container.
+type downloadObjectFunc func(ctx context.Context, objectName string) (*DownloadResult, error)
+
+// downloadContainer will download all objects in a given container. The
+// objects of each listed page are downloaded by up to concurrency
+// goroutines. If download is nil, downloadObject is used with opts.
+func downloadContainer(ctx context.Context, client *gophercloud.ServiceClient, containerName string, opts *DownloadOpts, concurrency int, download downloadObjectFunc) ([]DownloadResult, error) {
 	listOpts := objects.ListOpts{
 		Prefix:    opts.Prefix,
 		Delimiter: opts.Delimiter,
 	}
 
+	if download == nil {
+		download = func(ctx context.Context, objectName string) (*DownloadResult, error) {
+			return downloadObject(ctx, client, containerName, objectName, opts)
+		}
+	}
+
 	var downloadResults []DownloadResult
 	err := objects.List(client, containerName, listOpts).EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
 		objectList, err := objects.ExtractNames(page)
@@ -360,15 +677,24 @@ func downloadContainer(ctx context.Context, client *gophercloud.ServiceClient, c
 			return false, fmt.Errorf("error listing container %s: %s", containerName, err)
 		}
 
-		for _, objectName := range objectList {
-			result, err := downloadObject(ctx, client, containerName, objectName, opts)
+		results := make([]DownloadResult, len(objectList))
+		err = forEachConcurrently(ctx, concurrency, len(objectList), func(ctx context.Context, i int) error {
+			objectName := objectList[i]
+
+			result, err := download(ctx, objectName)
 			if err != nil {
-				return false, fmt.Errorf("error downloading object %s/%s: %s", containerName, objectName, err)
+				return fmt.Errorf("error downloading object %s/%s: %s", containerName, objectName, err)
 			}
 
-			downloadResults = append(downloadResults, *result)
+			results[i] = *result
+			return nil
+		})
+		if err != nil {
+			return false, err
 		}
 
+		downloadResults = append(downloadResults, results...)
+
 		return true, nil
 	})
 
@@ -0,0 +1,396 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+)
+
+// ContainerFS is a read-only file system of the objects of a container.
+// Pseudo-directories are derived from the "/" delimiter of the object names
+// and from directory markers. The Mtime metadata set by Upload is used as
+// the modification time of the files.
+type ContainerFS struct {
+	ctx       context.Context
+	client    *gophercloud.ServiceClient
+	container string
+	prefix    string
+
+	// ReaderOpts are the options of the ObjectReader of opened files.
+	ReaderOpts *ObjectReaderOpts
+}
+
+var (
+	_ fs.FS          = (*ContainerFS)(nil)
+	_ fs.ReadDirFS   = (*ContainerFS)(nil)
+	_ fs.StatFS      = (*ContainerFS)(nil)
+	_ fs.ReadDirFile = (*containerDir)(nil)
+)
+
+// NewContainerFS returns a file system of a container. The container name
+// may contain a pseudo-folder, e.g. "container/folder", to use it as the
+// root directory. The context is used for all requests of the file system.
+func NewContainerFS(ctx context.Context, client *gophercloud.ServiceClient, containerName string) *ContainerFS {
+	container, pseudoFolder := ContainerPartition(containerName)
+
+	var prefix string
+	if pseudoFolder != "" {
+		prefix = strings.TrimSuffix(pseudoFolder, "/") + "/"
+	}
+
+	return &ContainerFS{
+		ctx:       ctx,
+		client:    client,
+		container: container,
+		prefix:    prefix,
+	}
+}
+
+// objectName returns the object name of a valid path.
+func (fsys *ContainerFS) objectName(name string) string {
+	if name == "." {
+		return fsys.prefix
+	}
+	return fsys.prefix + name
+}
+
+// Open opens a file or a directory.
+func (fsys *ContainerFS) Open(name string) (fs.File, error) {
+	headers, info, err := fsys.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &containerDir{fsys: fsys, name: name, info: info}, nil
+	}
+
+	r, err := newObjectReader(fsys.ctx, fsys.client, fsys.container, fsys.objectName(name), headers, fsys.readerOpts())
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &containerFile{ObjectReader: r, info: info}, nil
+}
+
+// Stat returns the file info of a file or a directory.
+func (fsys *ContainerFS) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := fsys.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ReadDir returns the entries of a directory sorted by their names.
+func (fsys *ContainerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// An object takes precedence over a pseudo-directory of the same name,
+	// like it does in Open.
+	if name != "." {
+		_, info, err := fsys.stat("readdir", name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+
+	entries, err := fsys.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+func (fsys *ContainerFS) readerOpts() *ObjectReaderOpts {
+	if fsys.ReaderOpts == nil {
+		return &ObjectReaderOpts{}
+	}
+	return fsys.ReaderOpts
+}
+
+// stat returns the headers of an object and its file info. The headers are
+// nil for directories.
+func (fsys *ContainerFS) stat(op, name string) (*objects.GetHeader, *fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return nil, &fileInfo{name: ".", dir: true}, nil
+	}
+
+	objectName := fsys.objectName(name)
+
+	headers, info, err := fsys.statObject(objectName)
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if info != nil {
+		return headers, info, nil
+	}
+
+	// A pseudo-directory exists, when any object name starts with it.
+	listOpts := objects.ListOpts{
+		Limit:  1,
+		Prefix: objectName + "/",
+	}
+
+	var exists bool
+	err = objects.List(fsys.client, fsys.container, listOpts).EachPage(fsys.ctx, func(_ context.Context, page pagination.Page) (bool, error) {
+		names, err := objects.ExtractNames(page)
+		if err != nil {
+			return false, err
+		}
+		exists = len(names) > 0
+		return false, nil
+	})
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	if !exists {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return nil, &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// statObject returns the headers and the file info of an object or of a
+// directory marker. It returns no file info, if neither exists.
+func (fsys *ContainerFS) statObject(objectName string) (*objects.GetHeader, *fileInfo, error) {
+	for _, v := range []string{objectName, objectName + "/"} {
+		res := objects.Get(fsys.ctx, fsys.client, fsys.container, v, nil)
+		if res.Err != nil {
+			if gophercloud.ResponseCodeIs(res.Err, http.StatusNotFound) {
+				continue
+			}
+			return nil, nil, res.Err
+		}
+
+		headers, err := res.Extract()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		metadata, err := res.ExtractMetadata()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		info := &fileInfo{
+			name:    path.Base(objectName),
+			size:    headers.ContentLength,
+			modTime: headers.LastModified,
+			dir:     strings.HasSuffix(v, "/") || slices.Contains(knownDirMarkers, GetContentType(headers.ContentType)),
+			sys:     headers,
+		}
+		if mtime, err := parseMtime(metadata["Mtime"]); err == nil {
+			info.modTime = mtime
+		}
+		if info.dir {
+			info.size = 0
+			headers = nil
+		}
+
+		return headers, info, nil
+	}
+
+	return nil, nil, nil
+}
+
+// readDir lists the entries of a directory.
+func (fsys *ContainerFS) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := fsys.objectName(name)
+	if name != "." {
+		prefix += "/"
+	}
+
+	listOpts := objects.ListOpts{
+		Delimiter: "/",
+		Prefix:    prefix,
+	}
+
+	allPages, err := objects.List(fsys.client, fsys.container, listOpts).AllPages(fsys.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allObjects, err := objects.ExtractInfo(allPages)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*dirEntry)
+	for _, object := range allObjects {
+		objectName := object.Name
+		if object.Subdir != "" {
+			objectName = object.Subdir
+		}
+
+		entryName := strings.TrimSuffix(strings.TrimPrefix(objectName, prefix), "/")
+		if entryName == "" {
+			// the directory marker of the directory itself
+			continue
+		}
+
+		entry := &dirEntry{
+			fsys:       fsys,
+			objectName: objectName,
+			info: fileInfo{
+				name:    entryName,
+				size:    object.Bytes,
+				modTime: object.LastModified,
+				dir:     object.Subdir != "" || strings.HasSuffix(objectName, "/") || slices.Contains(knownDirMarkers, GetContentType(object.ContentType)),
+			},
+		}
+		if entry.info.dir {
+			entry.info.size = 0
+		}
+
+		// An object, a directory marker and the objects in the directory
+		// may be listed as separate entries of the same name. The object
+		// takes precedence, like it does in stat.
+		if existing, ok := entries[entryName]; ok && existing.objectName == prefix+entryName {
+			continue
+		}
+		entries[entryName] = entry
+	}
+
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return result, nil
+}
+
+// fileInfo implements fs.FileInfo for objects and pseudo-directories.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	sys     any
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() any           { return fi.sys }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// dirEntry implements fs.DirEntry. The file info of an object is retrieved
+// by Info, because the listing does not contain the Mtime metadata.
+type dirEntry struct {
+	fsys       *ContainerFS
+	objectName string
+	info       fileInfo
+}
+
+func (e *dirEntry) Name() string      { return e.info.name }
+func (e *dirEntry) IsDir() bool       { return e.info.dir }
+func (e *dirEntry) Type() fs.FileMode { return e.info.Mode().Type() }
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	_, info, err := e.fsys.statObject(strings.TrimSuffix(e.objectName, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil {
+		// pseudo-directories without a directory marker
+		if e.info.dir {
+			return &e.info, nil
+		}
+		return nil, fs.ErrNotExist
+	}
+
+	return info, nil
+}
+
+// containerFile implements fs.File for objects. It also implements
+// io.ReaderAt and io.Seeker.
+type containerFile struct {
+	*ObjectReader
+	info *fileInfo
+}
+
+func (f *containerFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// containerDir implements fs.ReadDirFile for pseudo-directories.
+type containerDir struct {
+	fsys    *ContainerFS
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	loaded  bool
+}
+
+func (d *containerDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *containerDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *containerDir) Close() error {
+	return nil
+}
+
+func (d *containerDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.fsys.readDir(d.name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+
+	return entries, nil
+}
@@ -7,7 +7,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +18,10 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
 )
 
+// maxManifestDepth is the maximum nesting depth of static large objects,
+// the same as the default of the Swift SLO middleware.
+const maxManifestDepth = 10
+
 // Manifest represents an object manifest.
 type Manifest struct {
 	Bytes        int64     `json:"bytes"`
@@ -22,6 +29,13 @@ type Manifest struct {
 	Hash         string    `json:"hash"`
 	Name         string    `json:"name"`
 	LastModified time.Time `json:"-"`
+
+	// Range is the byte range of the segment, which is part of the large
+	// object, e.g. "0-1023". Hash is the checksum of the whole segment.
+	Range string `json:"range,omitempty"`
+
+	// SubSLO is true, when the segment is a static large object itself.
+	SubSLO bool `json:"sub_slo,omitempty"`
 }
 
 func (r *Manifest) UnmarshalJSON(b []byte) error {
@@ -60,20 +74,45 @@ type GetManifestOpts struct {
 	StaticLargeObject bool
 }
 
+// formatObjectManifest returns the X-Object-Manifest header of a dynamic
+// large object, whose segments are the objects of a container starting
+// with the prefix. Both are URL-quoted the same way as python-swiftclient
+// does.
+func formatObjectManifest(containerName, prefix string) string {
+	return url.PathEscape(containerName) + "/" + escapeObjectName(prefix)
+}
+
+// parseObjectManifest returns the unquoted container and object prefix of
+// the X-Object-Manifest header of a dynamic large object.
+func parseObjectManifest(objectManifest string) (string, string, error) {
+	containerName, prefix, ok := strings.Cut(objectManifest, "/")
+	if !ok {
+		return "", "", fmt.Errorf("unable to parse object manifest %s", objectManifest)
+	}
+
+	containerName, err := url.PathUnescape(containerName)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to parse object manifest %s: %s", objectManifest, err)
+	}
+
+	prefix, err = url.PathUnescape(prefix)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to parse object manifest %s: %s", objectManifest, err)
+	}
+
+	return containerName, prefix, nil
+}
+
 // https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1916
 func GetManifest(ctx context.Context, client *gophercloud.ServiceClient, opts GetManifestOpts) ([]Manifest, error) {
 	var manifest []Manifest
 
-	// TODO: test this
 	if opts.ObjectManifest != "" {
-		v := strings.SplitN(opts.ObjectManifest, "/", 2)
-		if len(v) != 2 {
-			return nil, fmt.Errorf("unable to parse object manifest %s", opts.ObjectManifest)
+		sContainer, sPrefix, err := parseObjectManifest(opts.ObjectManifest)
+		if err != nil {
+			return nil, err
 		}
 
-		sContainer := v[0]
-		sPrefix := v[1]
-
 		listOpts := objects.ListOpts{
 			Prefix: sPrefix,
 		}
@@ -110,50 +149,191 @@ func GetManifest(ctx context.Context, client *gophercloud.ServiceClient, opts Ge
 
 	if opts.StaticLargeObject {
 		if opts.Manifest == "" {
-			downloadOpts := objects.DownloadOpts{
-				MultipartManifest: "get",
+			return getSLOManifest(ctx, client, opts.ContainerName, opts.ObjectName, 1, nil)
+		}
+
+		return manifest, nil
+	}
+
+	m := Manifest{
+		Hash:  opts.ETag,
+		Bytes: opts.ContentLength,
+	}
+
+	manifest = append(manifest, m)
+
+	return manifest, nil
+}
+
+// getSLOManifest returns the segments of a static large object. Nested static
+// large objects are expanded recursively up to maxManifestDepth and their
+// names are passed to subManifest, when it is not nil.
+//
+// https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1948
+func getSLOManifest(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, depth int, subManifest func(name string)) ([]Manifest, error) {
+	if depth > maxManifestDepth {
+		return nil, fmt.Errorf("manifest of %s/%s exceeds the maximum depth of %d", containerName, objectName, maxManifestDepth)
+	}
+
+	downloadOpts := objects.DownloadOpts{
+		MultipartManifest: "get",
+	}
+	res := objects.Download(ctx, client, containerName, objectName, downloadOpts)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	body, err := res.ExtractContent()
+	if err != nil {
+		return nil, err
+	}
+
+	multipartManifest, err := ExtractMultipartManifest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest []Manifest
+	for _, obj := range multipartManifest {
+		if !obj.SubSLO {
+			m := Manifest{
+				Bytes:        obj.Bytes,
+				ContentType:  obj.ContentType,
+				Hash:         obj.Hash,
+				LastModified: obj.LastModified,
+				Name:         obj.Name,
 			}
-			res := objects.Download(ctx, client, opts.ContainerName, opts.ObjectName, downloadOpts)
-			if res.Err != nil {
-				return nil, res.Err
+
+			if obj.Range != "" {
+				start, length, err := parseSegmentRange(obj.Range, obj.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("invalid range of segment %s: %s", obj.Name, err)
+				}
+
+				// a range covering the whole segment is the same as no range
+				if start != 0 || length != obj.Bytes {
+					m.Range = fmt.Sprintf("%d-%d", start, start+length-1)
+					m.Bytes = length
+				}
 			}
 
-			body, err := res.ExtractContent()
+			manifest = append(manifest, m)
+			continue
+		}
+
+		sContainer, sObject, ok := strings.Cut(strings.TrimPrefix(obj.Name, "/"), "/")
+		if !ok {
+			return nil, fmt.Errorf("unable to parse segment name %s", obj.Name)
+		}
+
+		if subManifest != nil {
+			subManifest(obj.Name)
+		}
+
+		segments, err := getSLOManifest(ctx, client, sContainer, sObject, depth+1, subManifest)
+		if err != nil {
+			return nil, err
+		}
+
+		if obj.Range != "" {
+			start, length, err := parseSegmentRange(obj.Range, obj.Bytes)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("invalid range of segment %s: %s", obj.Name, err)
 			}
 
-			multipartManifest, err := ExtractMultipartManifest(body)
+			segments, err = sliceManifest(segments, start, length)
 			if err != nil {
 				return nil, err
 			}
-
-			for _, obj := range multipartManifest {
-				// TODO: support sub_slo
-				m := Manifest{
-					Bytes:        obj.Bytes,
-					ContentType:  obj.ContentType,
-					Hash:         obj.Hash,
-					LastModified: obj.LastModified,
-					Name:         obj.Name,
-				}
-				manifest = append(manifest, m)
-			}
 		}
 
-		return manifest, nil
+		manifest = append(manifest, segments...)
 	}
 
-	m := Manifest{
-		Hash:  opts.ETag,
-		Bytes: opts.ContentLength,
+	return manifest, nil
+}
+
+// parseSegmentRange parses the range of a segment of the specified size and
+// returns the offset and the length of the range. The range may be either
+// "first-last", "first-" or "-suffix".
+func parseSegmentRange(r string, size int64) (int64, int64, error) {
+	first, last, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("unable to parse range %s", r)
 	}
 
-	manifest = append(manifest, m)
+	var start, end int64
+	var err error
+	switch {
+	case first == "":
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		start = max(size-end, 0)
+		end = size
+	case last == "":
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		end = size
+	default:
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		end = min(end+1, size)
+	}
 
-	return manifest, nil
+	if start < 0 || start >= end {
+		return 0, 0, fmt.Errorf("range %s is not satisfiable for %d bytes", r, size)
+	}
+
+	return start, end - start, nil
+}
+
+// sliceManifest returns the segments covering the specified byte range of a
+// manifest. The segments, which are covered partially, get a range.
+func sliceManifest(manifest []Manifest, start, length int64) ([]Manifest, error) {
+	var result []Manifest
+	var offset int64
+	for _, m := range manifest {
+		segStart, segEnd := offset, offset+m.Bytes
+		offset = segEnd
+
+		lo, hi := max(start, segStart), min(start+length, segEnd)
+		if lo >= hi {
+			continue
+		}
+
+		if lo != segStart || hi != segEnd {
+			var base int64
+			if m.Range != "" {
+				var err error
+				base, _, err = parseSegmentRange(m.Range, math.MaxInt64)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			m.Range = fmt.Sprintf("%d-%d", base+lo-segStart, base+hi-segStart-1)
+			m.Bytes = hi - lo
+		}
+
+		result = append(result, m)
+	}
+
+	return result, nil
 }
 
+// IsIdentical compares the segments of a manifest to a local file. Segments
+// with a range cannot be compared, because their checksum covers the whole
+// segment, so such manifests are never identical.
 func IsIdentical(manifest []Manifest, path string) (bool, error) {
 	if path == "" {
 		return false, nil
@@ -168,14 +348,17 @@ func IsIdentical(manifest []Manifest, path string) (bool, error) {
 	reader := bufio.NewReader(f)
 
 	for _, data := range manifest {
+		if data.Range != "" {
+			return false, nil
+		}
+
+		// The segment is hashed while it is read, so that large segments
+		// are not held in memory.
 		hash := md5.New()
-		buf := make([]byte, data.Bytes)
-		n, err := reader.Read(buf)
-		if err != nil && err != io.EOF {
+		if _, err := io.CopyN(hash, reader, data.Bytes); err != nil && err != io.EOF {
 			return false, err
 		}
 
-		hash.Write(buf[:n])
 		checksum := fmt.Sprintf("%x", hash.Sum(nil))
 		if checksum != data.Hash {
 			return false, nil
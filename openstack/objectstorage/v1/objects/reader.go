@@ -0,0 +1,430 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+)
+
+const (
+	// DefaultReadBlockSize is the default size of the blocks fetched by an
+	// ObjectReader.
+	DefaultReadBlockSize = 1024 * 1024
+
+	// DefaultReadCacheBlocks is the default number of blocks cached by an
+	// ObjectReader.
+	DefaultReadCacheBlocks = 4
+)
+
+// ObjectReaderOpts represents options used for opening an ObjectReader.
+type ObjectReaderOpts struct {
+	// BlockSize is the size of the blocks fetched by a single range request.
+	// If zero, DefaultReadBlockSize is used.
+	BlockSize int64
+
+	// CacheBlocks is the number of recently used blocks kept in memory. If
+	// zero, DefaultReadCacheBlocks is used.
+	CacheBlocks int
+
+	// ReadAhead is the number of blocks fetched in the background ahead of
+	// sequential reads by Read. If zero, one block is read ahead. ReadAt
+	// does not read ahead.
+	ReadAhead int
+}
+
+// readerSegment represents a segment of a static large object read by an
+// ObjectReader.
+type readerSegment struct {
+	container string
+	object    string
+	etag      string
+
+	// start is the offset of the segment data in the segment object.
+	start int64
+
+	// offset is the offset of the segment in the large object.
+	offset int64
+	length int64
+}
+
+// readerBlock represents a cached block of an ObjectReader.
+type readerBlock struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// ObjectReader provides random access to a remote object using range
+// requests. The blocks of static large objects are fetched directly from
+// their segments. ReadAt can be called concurrently, but Read and Seek must
+// not.
+type ObjectReader struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	client    *gophercloud.ServiceClient
+	container string
+	object    string
+	etag      string
+	size      int64
+	segments  []readerSegment
+
+	blockSize   int64
+	cacheBlocks int
+	readAhead   int
+
+	// offset is the offset of the next Read.
+	offset int64
+
+	mu     sync.Mutex
+	blocks map[int64]*readerBlock
+	lru    []int64
+	closed bool
+}
+
+// OpenObject opens an ObjectReader of an object. The object must not be
+// modified while it is read; reads fail, when the etag of the object or of
+// its segments changes.
+func OpenObject(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, opts *ObjectReaderOpts) (*ObjectReader, error) {
+	if opts == nil {
+		opts = &ObjectReaderOpts{}
+	}
+
+	headers, err := objects.Get(ctx, client, containerName, objectName, nil).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, err)
+	}
+
+	return newObjectReader(ctx, client, containerName, objectName, headers, opts)
+}
+
+// newObjectReader returns an ObjectReader of an object with the specified
+// headers.
+func newObjectReader(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, headers *objects.GetHeader, opts *ObjectReaderOpts) (*ObjectReader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &ObjectReader{
+		ctx:         ctx,
+		cancel:      cancel,
+		client:      client,
+		container:   containerName,
+		object:      objectName,
+		size:        headers.ContentLength,
+		blockSize:   opts.BlockSize,
+		cacheBlocks: opts.CacheBlocks,
+		readAhead:   opts.ReadAhead,
+		blocks:      make(map[int64]*readerBlock),
+	}
+
+	if r.blockSize <= 0 {
+		r.blockSize = DefaultReadBlockSize
+	}
+	if r.readAhead <= 0 {
+		r.readAhead = 1
+	}
+	if r.cacheBlocks <= 0 {
+		r.cacheBlocks = DefaultReadCacheBlocks
+	}
+	r.cacheBlocks = max(r.cacheBlocks, r.readAhead+1)
+
+	// The etag of a dynamic large object changes with its segments, so it
+	// is not used as a precondition.
+	if headers.ObjectManifest == "" {
+		r.etag = strings.Trim(headers.ETag, `"`)
+	}
+
+	if headers.StaticLargeObject {
+		manifest, err := getSLOManifest(ctx, client, containerName, objectName, 1, nil)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("unable to get manifest for %s/%s: %s", containerName, objectName, err)
+		}
+
+		r.segments, err = newReaderSegments(manifest)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("unable to parse manifest for %s/%s: %s", containerName, objectName, err)
+		}
+
+		if n := len(r.segments); n > 0 && r.segments[n-1].offset+r.segments[n-1].length != r.size {
+			cancel()
+			return nil, fmt.Errorf("manifest for %s/%s does not match its size", containerName, objectName)
+		}
+	}
+
+	return r, nil
+}
+
+// newReaderSegments returns the segments of an expanded manifest with their
+// offsets.
+func newReaderSegments(manifest []Manifest) ([]readerSegment, error) {
+	segments := make([]readerSegment, 0, len(manifest))
+
+	var offset int64
+	for _, m := range manifest {
+		sContainer, sObject, ok := strings.Cut(strings.TrimPrefix(m.Name, "/"), "/")
+		if !ok {
+			return nil, fmt.Errorf("unable to parse segment name %s", m.Name)
+		}
+
+		segment := readerSegment{
+			container: sContainer,
+			object:    sObject,
+			etag:      m.Hash,
+			offset:    offset,
+			length:    m.Bytes,
+		}
+
+		if m.Range != "" {
+			start, _, err := parseSegmentRange(m.Range, m.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			segment.start = start
+		}
+
+		segments = append(segments, segment)
+		offset += m.Bytes
+	}
+
+	return segments, nil
+}
+
+// Size returns the size of the object.
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+
+	var n int
+	for n < len(p) && off+int64(n) < r.size {
+		pos := off + int64(n)
+		index := pos / r.blockSize
+
+		data, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+
+		n += copy(p[n:], data[pos-index*r.blockSize:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Read implements io.Reader. The following blocks are fetched in the
+// background.
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+
+	if n > 0 {
+		index := (r.offset - 1) / r.blockSize
+		for i := 1; i <= r.readAhead; i++ {
+			r.prefetch(index + int64(i))
+		}
+	}
+
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// Close cancels the pending requests and releases the cached blocks.
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fs.ErrClosed
+	}
+
+	r.closed = true
+	r.cancel()
+	r.blocks = nil
+	r.lru = nil
+
+	return nil
+}
+
+// block returns the data of a block, fetching it, if it is not cached.
+func (r *ObjectReader) block(index int64) ([]byte, error) {
+	b, fetch, err := r.cachedBlock(index)
+	if err != nil {
+		return nil, err
+	}
+
+	if fetch {
+		r.fetch(index, b)
+	}
+
+	<-b.done
+	return b.data, b.err
+}
+
+// prefetch fetches a block in the background, if it is not cached.
+func (r *ObjectReader) prefetch(index int64) {
+	if index*r.blockSize >= r.size {
+		return
+	}
+
+	b, fetch, err := r.cachedBlock(index)
+	if err == nil && fetch {
+		go r.fetch(index, b)
+	}
+}
+
+// cachedBlock returns the cached block or adds a new one, which has to be
+// fetched by the caller.
+func (r *ObjectReader) cachedBlock(index int64) (*readerBlock, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, false, fs.ErrClosed
+	}
+
+	if b, ok := r.blocks[index]; ok {
+		// mark the block as recently used
+		if i := slices.Index(r.lru, index); i >= 0 {
+			r.lru = append(slices.Delete(r.lru, i, i+1), index)
+		}
+		return b, false, nil
+	}
+
+	b := &readerBlock{done: make(chan struct{})}
+	r.blocks[index] = b
+	r.lru = append(r.lru, index)
+
+	// evict the least recently used blocks
+	for len(r.lru) > r.cacheBlocks {
+		delete(r.blocks, r.lru[0])
+		r.lru = r.lru[1:]
+	}
+
+	return b, true, nil
+}
+
+// fetch fetches the data of a block. A failed block is removed from the
+// cache, so that it is fetched again by the next read.
+func (r *ObjectReader) fetch(index int64, b *readerBlock) {
+	defer close(b.done)
+
+	start := index * r.blockSize
+	end := min(start+r.blockSize, r.size)
+	b.data = make([]byte, end-start)
+	b.err = r.fetchRange(start, b.data)
+
+	if b.err != nil {
+		r.mu.Lock()
+		if r.blocks[index] == b {
+			delete(r.blocks, index)
+			r.lru = slices.DeleteFunc(r.lru, func(v int64) bool { return v == index })
+		}
+		r.mu.Unlock()
+	}
+}
+
+// fetchRange reads the data at the offset of the object. The data of static
+// large objects is read from their segments.
+func (r *ObjectReader) fetchRange(start int64, buf []byte) error {
+	if r.segments == nil {
+		return r.download(r.container, r.object, r.etag, start, buf)
+	}
+
+	i := sort.Search(len(r.segments), func(i int) bool {
+		return r.segments[i].offset+r.segments[i].length > start
+	})
+
+	for pos := start; pos < start+int64(len(buf)); i++ {
+		if i >= len(r.segments) {
+			return io.ErrUnexpectedEOF
+		}
+
+		s := r.segments[i]
+		end := min(start+int64(len(buf)), s.offset+s.length)
+		if err := r.download(s.container, s.object, s.etag, s.start+pos-s.offset, buf[pos-start:end-start]); err != nil {
+			return err
+		}
+
+		pos = end
+	}
+
+	return nil
+}
+
+// download reads a byte range of an object.
+func (r *ObjectReader) download(containerName, objectName, etag string, start int64, buf []byte) error {
+	if len(buf) == 0 {
+		return nil
+	}
+
+	downloadOpts := objects.DownloadOpts{
+		IfMatch: etag,
+		Range:   fmt.Sprintf("bytes=%d-%d", start, start+int64(len(buf))-1),
+	}
+
+	res := objects.Download(r.ctx, r.client, containerName, objectName, downloadOpts)
+	if res.Err != nil {
+		if gophercloud.ResponseCodeIs(res.Err, http.StatusPreconditionFailed) {
+			return fmt.Errorf("object %s/%s has changed", containerName, objectName)
+		}
+		return fmt.Errorf("error downloading range %s of object %s/%s: %s", downloadOpts.Range, containerName, objectName, res.Err)
+	}
+	defer res.Body.Close()
+
+	if start != 0 && res.Header.Get("Content-Range") == "" {
+		return fmt.Errorf("range requests are not supported for object %s/%s", containerName, objectName)
+	}
+
+	if _, err := io.ReadFull(res.Body, buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("error reading range %s of object %s/%s: %s", downloadOpts.Range, containerName, objectName, err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ type DownloadResult struct {
 	Action    string
 	Container string
 	Content   io.ReadCloser
+	ETag      string
 	Object    string
 	Path      string
 	PseudoDir bool
@@ -23,3 +24,47 @@ type UploadResult struct {
 	Status      string
 	Success     bool
 }
+
+type UploadArchiveResult struct {
+	Action    string
+	Container string
+	Created   int
+	Errors    []UploadResult
+	Path      string
+	Status    string
+	Success   bool
+}
+
+type SyncResult struct {
+	Action    string
+	Container string
+	Error     error
+	Object    string
+	Path      string
+	Status    string
+	Success   bool
+}
+
+type CopyResult struct {
+	Action          string
+	Container       string
+	LargeObject     bool
+	Object          string
+	SourceContainer string
+	SourceObject    string
+	Success         bool
+}
+
+type DeleteResult struct {
+	Action    string
+	Container string
+	Error     error
+	Object    string
+	Status    string
+	Success   bool
+}
+
+type FormPOSTResult struct {
+	Fields map[string]string
+	URL    string
+}
@@ -0,0 +1,540 @@
+package objects
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+)
+
+// SyncOpts represents options used for synchronizing a local directory
+// tree and the objects of a container.
+type SyncOpts struct {
+	// BandwidthLimiter limits the total bandwidth of the transfers.
+	BandwidthLimiter *BandwidthLimiter
+
+	// Changed will skip a file if the mtime and size of the source and
+	// destination are the same.
+	Changed bool
+
+	// Checksum will enforce a comparison of the md5sum/etag between the
+	// local and remote object to ensure the integrity of the transfers.
+	Checksum bool
+
+	// Concurrency is the number of files transferred in parallel. If zero,
+	// files are transferred sequentially.
+	Concurrency int
+
+	// Delete will delete the destination objects or files, which do not
+	// exist in the source. UploadDirectory refuses to delete objects, when
+	// SegmentContainer is the destination container.
+	Delete bool
+
+	// DryRun will only report the actions to be taken, without modifying
+	// the destination. Such actions have the "dry-run" status.
+	DryRun bool
+
+	// Progress is called, when a part of a file has been transferred.
+	Progress ProgressFunc
+
+	// SegmentContainer is a custom container name to store object segments.
+	SegmentContainer string
+
+	// SegmentSize is the size of each segment of uploaded large objects.
+	SegmentSize int64
+
+	// SkipIdentical will skip a file if the md5sum/etag of the source and
+	// destination are the same.
+	SkipIdentical bool
+
+	// StoragePolicy is the storage policy of the uploaded objects.
+	StoragePolicy string
+
+	// UseSLO will upload large objects using Static Large Object support.
+	UseSLO bool
+}
+
+// localEntry represents a file or an empty directory of a local tree.
+type localEntry struct {
+	path  string
+	name  string
+	isDir bool
+}
+
+// UploadDirectory uploads a local directory tree to a container. The
+// container name may contain a pseudo-folder, e.g. "container/folder", to
+// upload the tree under it. Empty directories are uploaded as directory
+// markers.
+func UploadDirectory(ctx context.Context, client *gophercloud.ServiceClient, containerName, dir string, opts *SyncOpts) ([]SyncResult, error) {
+	container, pseudoFolder := ContainerPartition(containerName)
+
+	// The segments of the uploaded objects would be deleted as extraneous
+	// objects of the container.
+	if opts.Delete && opts.SegmentContainer == container {
+		return nil, fmt.Errorf("segment container %s must not be the container, when extraneous objects are deleted", container)
+	}
+
+	var entries []localEntry
+	localNames := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		name := path.Join(pseudoFolder, filepath.ToSlash(rel))
+
+		switch {
+		case d.IsDir():
+			localNames[name] = true
+
+			children, err := os.ReadDir(p)
+			if err != nil {
+				return err
+			}
+			if len(children) == 0 {
+				entries = append(entries, localEntry{path: p, name: name, isDir: true})
+			}
+		case d.Type().IsRegular():
+			localNames[name] = true
+			entries = append(entries, localEntry{path: p, name: name})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %s", dir, err)
+	}
+
+	baseOpts := UploadOpts{
+		BandwidthLimiter: opts.BandwidthLimiter,
+		Changed:          opts.Changed,
+		Checksum:         opts.Checksum,
+		Progress:         opts.Progress,
+		SegmentContainer: opts.SegmentContainer,
+		SegmentSize:      opts.SegmentSize,
+		SkipIdentical:    opts.SkipIdentical,
+		StoragePolicy:    opts.StoragePolicy,
+		UseSLO:           opts.UseSLO,
+	}
+
+	if !opts.DryRun && len(entries) > 0 {
+		if err := createContainers(ctx, client, container, &baseOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]SyncResult, len(entries))
+	err = forEachConcurrently(ctx, opts.Concurrency, len(entries), func(ctx context.Context, i int) error {
+		entry := entries[i]
+
+		result := SyncResult{
+			Action:    "upload_object",
+			Container: container,
+			Object:    entry.name,
+			Path:      entry.path,
+		}
+		if entry.isDir {
+			result.Action = "create_dir_marker"
+		}
+
+		if opts.DryRun {
+			status := "dry-run"
+			if !entry.isDir {
+				v, err := compareObject(ctx, client, container, entry.name, entry.path, opts)
+				if err != nil {
+					return err
+				}
+				if v != "" {
+					status = v
+				}
+			}
+
+			result.Status = status
+			result.Success = true
+			results[i] = result
+			return nil
+		}
+
+		uploadOpts := baseOpts
+		uploadOpts.Path = entry.path
+
+		uploadResult, err := upload(ctx, client, container, entry.name, &uploadOpts)
+		if err != nil {
+			return fmt.Errorf("error uploading %s to %s/%s: %s", entry.path, container, entry.name, err)
+		}
+
+		result.Status = uploadResult.Status
+		if result.Status == "" {
+			result.Status = "uploaded"
+		}
+		result.Success = uploadResult.Success
+		results[i] = result
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Delete {
+		return results, nil
+	}
+
+	var prefix string
+	if pseudoFolder != "" {
+		prefix = pseudoFolder + "/"
+	}
+
+	remoteObjects, err := listObjects(ctx, client, container, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("error listing container %s: %s", container, err)
+	}
+
+	var extra []string
+	for _, object := range remoteObjects {
+		if !localNames[strings.TrimSuffix(object.Name, "/")] {
+			extra = append(extra, object.Name)
+		}
+	}
+
+	if opts.DryRun {
+		for _, objectName := range extra {
+			results = append(results, SyncResult{
+				Action:    "delete_object",
+				Container: container,
+				Object:    objectName,
+				Status:    "dry-run",
+				Success:   true,
+			})
+		}
+
+		return results, nil
+	}
+
+	if len(extra) == 0 {
+		return results, nil
+	}
+
+	deleteOpts := &DeleteOpts{
+		Concurrency: opts.Concurrency,
+	}
+
+	deleteResults, err := Delete(ctx, client, container, extra, deleteOpts)
+	if err != nil && deleteResults == nil {
+		return nil, err
+	}
+
+	for _, r := range deleteResults {
+		status := r.Status
+		if r.Success && status == "" {
+			status = "deleted"
+		}
+
+		results = append(results, SyncResult{
+			Action:    r.Action,
+			Container: r.Container,
+			Error:     r.Error,
+			Object:    r.Object,
+			Status:    status,
+			Success:   r.Success,
+		})
+	}
+
+	return results, err
+}
+
+// DownloadDirectory downloads the objects of a container to a local
+// directory tree. The container name may contain a pseudo-folder, e.g.
+// "container/folder", to download only the objects under it, relative to
+// it. Directory markers are created as directories.
+func DownloadDirectory(ctx context.Context, client *gophercloud.ServiceClient, containerName, dir string, opts *SyncOpts) ([]SyncResult, error) {
+	container, pseudoFolder := ContainerPartition(containerName)
+
+	var prefix string
+	if pseudoFolder != "" {
+		prefix = pseudoFolder + "/"
+	}
+
+	var mu sync.Mutex
+	remoteNames := make(map[string]bool)
+	syncResults := make(map[string]SyncResult)
+
+	download := func(ctx context.Context, objectName string) (*DownloadResult, error) {
+		downloadResult := &DownloadResult{
+			Container: container,
+			Object:    objectName,
+		}
+
+		rel := strings.TrimSuffix(strings.TrimPrefix(objectName, prefix), "/")
+		if rel == "" {
+			// the directory marker of the pseudo-folder itself
+			return downloadResult, nil
+		}
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("object name %s/%s is not a local path", container, objectName)
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(rel))
+
+		result, err := downloadSyncObject(ctx, client, container, objectName, filename, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		remoteNames[rel] = true
+		syncResults[objectName] = *result
+		mu.Unlock()
+
+		downloadResult.Action = result.Action
+		downloadResult.Path = filename
+		downloadResult.Success = result.Success
+
+		return downloadResult, nil
+	}
+
+	listOpts := &DownloadOpts{
+		Prefix: prefix,
+	}
+
+	downloadResults, err := downloadContainer(ctx, client, container, listOpts, opts.Concurrency, download)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SyncResult
+	for _, r := range downloadResults {
+		if result, ok := syncResults[r.Object]; ok {
+			results = append(results, result)
+		}
+	}
+
+	if !opts.Delete {
+		return results, nil
+	}
+
+	var extra []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if !remoteNames[filepath.ToSlash(rel)] {
+			extra = append(extra, p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %s", dir, err)
+	}
+
+	for _, p := range extra {
+		result := SyncResult{
+			Action:  "delete_file",
+			Path:    p,
+			Status:  "dry-run",
+			Success: true,
+		}
+
+		if !opts.DryRun {
+			if err := os.Remove(p); err != nil {
+				return nil, fmt.Errorf("error deleting file %s: %s", p, err)
+			}
+			result.Status = "deleted"
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// listObjects lists the objects of a container, which names start with the
+// specified prefix.
+func listObjects(ctx context.Context, client *gophercloud.ServiceClient, containerName, prefix string) ([]objects.Object, error) {
+	listOpts := objects.ListOpts{
+		Prefix: prefix,
+	}
+
+	allPages, err := objects.List(client, containerName, listOpts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return objects.ExtractInfo(allPages)
+}
+
+// downloadSyncObject downloads an object to a local file, unless it is
+// skipped according to the Changed and SkipIdentical options. The Mtime
+// metadata of the object is set as the modification time of the file.
+func downloadSyncObject(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName, filename string, opts *SyncOpts) (*SyncResult, error) {
+	result := &SyncResult{
+		Action:    "download_object",
+		Container: containerName,
+		Object:    objectName,
+		Path:      filename,
+		Status:    "dry-run",
+		Success:   true,
+	}
+
+	objectResult := objects.Get(ctx, client, containerName, objectName, nil)
+	if objectResult.Err != nil {
+		return nil, fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, objectResult.Err)
+	}
+
+	headers, err := objectResult.Extract()
+	if err != nil {
+		return nil, fmt.Errorf("error extracting headers of object %s/%s: %s", containerName, objectName, err)
+	}
+
+	metadata, err := objectResult.ExtractMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("error extracting metadata of object %s/%s: %s", containerName, objectName, err)
+	}
+
+	if strings.HasSuffix(objectName, "/") || slices.Contains(knownDirMarkers, GetContentType(headers.ContentType)) {
+		result.Action = "create_dir"
+		if !opts.DryRun {
+			if err := os.MkdirAll(filename, 0777); err != nil {
+				return nil, fmt.Errorf("error creating directory %s: %s", filename, err)
+			}
+			result.Status = "created"
+		}
+		return result, nil
+	}
+
+	status, err := compareHeaders(ctx, client, containerName, objectName, filename, headers, metadata, opts)
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		result.Status = status
+		return result, nil
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	downloadOpts := &DownloadOpts{
+		BandwidthLimiter: opts.BandwidthLimiter,
+		Checksum:         opts.Checksum,
+		IgnoreMtime:      true,
+		OutFile:          filename,
+		Progress:         opts.Progress,
+	}
+	if _, err := downloadRetrievedObject(ctx, client, containerName, objectName, objectResult, downloadOpts); err != nil {
+		return nil, err
+	}
+
+	if mtime, err := parseMtime(metadata["Mtime"]); err == nil {
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			return nil, fmt.Errorf("error updating mtime for %s: %s", filename, err)
+		}
+	}
+
+	result.Status = "downloaded"
+	return result, nil
+}
+
+// compareObject compares an object and a local file according to the
+// Changed and SkipIdentical options. It returns the status of the skipped
+// transfer, or an empty string, when the object and the file differ.
+func compareObject(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName, filename string, opts *SyncOpts) (string, error) {
+	if !opts.Changed && !opts.SkipIdentical {
+		return "", nil
+	}
+
+	objectResult := objects.Get(ctx, client, containerName, objectName, nil)
+	if objectResult.Err != nil {
+		if gophercloud.ResponseCodeIs(objectResult.Err, http.StatusNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error retrieving object %s/%s: %s", containerName, objectName, objectResult.Err)
+	}
+
+	headers, err := objectResult.Extract()
+	if err != nil {
+		return "", fmt.Errorf("error extracting headers of object %s/%s: %s", containerName, objectName, err)
+	}
+
+	metadata, err := objectResult.ExtractMetadata()
+	if err != nil {
+		return "", fmt.Errorf("error extracting metadata of object %s/%s: %s", containerName, objectName, err)
+	}
+
+	return compareHeaders(ctx, client, containerName, objectName, filename, headers, metadata, opts)
+}
+
+// compareHeaders compares the headers and metadata of an object and a local
+// file like compareObject.
+func compareHeaders(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName, filename string, headers *objects.GetHeader, metadata map[string]string, opts *SyncOpts) (string, error) {
+	if !opts.Changed && !opts.SkipIdentical {
+		return "", nil
+	}
+
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error retrieving file stats of %s: %s", filename, err)
+	}
+
+	if opts.Changed {
+		if metadata["Mtime"] == formatMtime(fileInfo.ModTime()) && headers.ContentLength == fileInfo.Size() {
+			return "skip-changed", nil
+		}
+	}
+
+	if opts.SkipIdentical {
+		mo := GetManifestOpts{
+			ContainerName:     containerName,
+			ContentLength:     headers.ContentLength,
+			ETag:              headers.ETag,
+			ObjectManifest:    headers.ObjectManifest,
+			ObjectName:        objectName,
+			StaticLargeObject: headers.StaticLargeObject,
+		}
+
+		manifestData, err := GetManifest(ctx, client, mo)
+		if err != nil {
+			return "", fmt.Errorf("unable to get manifest for %s/%s: %s", containerName, objectName, err)
+		}
+
+		ok, err := IsIdentical(manifestData, filename)
+		if err != nil {
+			return "", fmt.Errorf("error comparing object %s/%s and path %s: %s", containerName, objectName, filename, err)
+		}
+
+		if ok {
+			return "skip-identical", nil
+		}
+	}
+
+	return "", nil
+}
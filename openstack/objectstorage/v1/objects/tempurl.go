@@ -0,0 +1,278 @@
+package objects
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/accounts"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/containers"
+)
+
+// DefaultTempURLDigest is the default digest of temporary URL and FormPOST
+// signatures.
+const DefaultTempURLDigest = "sha256"
+
+// tempURLDigests maps the digests supported by Swift to their hash
+// functions.
+var tempURLDigests = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// TempURLOpts represents options used for generating a temporary URL.
+type TempURLOpts struct {
+	// Digest is the hash function of the signature, either "sha1", "sha256"
+	// or "sha512". If empty, DefaultTempURLDigest is used.
+	Digest string
+
+	// Expires is the time the temporary URL expires. It overrides TTL.
+	Expires time.Time
+
+	// Filename overrides the file name of the downloaded object.
+	Filename string
+
+	// Inline will have the object displayed by the browser instead of
+	// being downloaded.
+	Inline bool
+
+	// IPRange restricts the temporary URL to an IP address or an IP network
+	// in CIDR notation, e.g. "192.168.0.0/24".
+	IPRange string
+
+	// Key is the temporary URL key. If empty, the key is retrieved from the
+	// container metadata or, if it is not set there, from the account
+	// metadata.
+	Key string
+
+	// Method is the allowed HTTP method, e.g. "GET", "HEAD" or "PUT". If
+	// empty, "GET" is used.
+	Method string
+
+	// Prefix will have the temporary URL valid for all objects, which names
+	// start with the object name.
+	Prefix bool
+
+	// TTL is the duration the temporary URL is valid.
+	TTL time.Duration
+}
+
+// FormPOSTOpts represents options used for generating a FormPOST signature.
+type FormPOSTOpts struct {
+	// Digest is the hash function of the signature, either "sha1", "sha256"
+	// or "sha512". If empty, DefaultTempURLDigest is used.
+	Digest string
+
+	// Expires is the time the form expires. It overrides TTL.
+	Expires time.Time
+
+	// Key is the temporary URL key. If empty, the key is retrieved from the
+	// container metadata or, if it is not set there, from the account
+	// metadata.
+	Key string
+
+	// MaxFileCount is the maximum number of uploaded files. If zero, 1 is
+	// used.
+	MaxFileCount int
+
+	// MaxFileSize is the maximum size of an uploaded file.
+	MaxFileSize int64
+
+	// Prefix is the prefix of the names of the uploaded objects.
+	Prefix string
+
+	// Redirect is the URL the browser is redirected to after the upload.
+	Redirect string
+
+	// TTL is the duration the form is valid.
+	TTL time.Duration
+}
+
+// TempURL generates a temporary URL for an object. The signature is
+// computed locally; only the key may be retrieved from the container or
+// account metadata.
+//
+// https://docs.openstack.org/swift/latest/api/temporary_url_middleware.html
+func TempURL(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, opts *TempURLOpts) (string, error) {
+	method := opts.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	expires, err := tempURLExpires(opts.Expires, opts.TTL)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := getTempURLKey(ctx, client, containerName, opts.Key)
+	if err != nil {
+		return "", err
+	}
+
+	objectURL, objectPath, err := tempURLPath(client, containerName, objectName)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Prefix {
+		objectPath = "prefix:" + objectPath
+	}
+
+	hmacBody := fmt.Sprintf("%s\n%d\n%s", method, expires, objectPath)
+	if opts.IPRange != "" {
+		hmacBody = "ip=" + opts.IPRange + "\n" + hmacBody
+	}
+
+	sig, err := tempURLSignature(opts.Digest, key, hmacBody)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("temp_url_sig", sig)
+	query.Set("temp_url_expires", strconv.FormatInt(expires, 10))
+	if opts.Prefix {
+		query.Set("temp_url_prefix", objectName)
+	}
+	if opts.IPRange != "" {
+		query.Set("temp_url_ip_range", opts.IPRange)
+	}
+	if opts.Filename != "" {
+		query.Set("filename", opts.Filename)
+	}
+	if opts.Inline {
+		query.Set("inline", "")
+	}
+
+	return objectURL + "?" + query.Encode(), nil
+}
+
+// FormPOST generates the URL and the signed form fields of an HTML form,
+// which uploads files to a container. The files have to be the last fields
+// of the form.
+//
+// https://docs.openstack.org/swift/latest/api/form_post_middleware.html
+func FormPOST(ctx context.Context, client *gophercloud.ServiceClient, containerName string, opts *FormPOSTOpts) (*FormPOSTResult, error) {
+	expires, err := tempURLExpires(opts.Expires, opts.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxFileSize <= 0 {
+		return nil, fmt.Errorf("MaxFileSize must be greater than zero")
+	}
+
+	maxFileCount := opts.MaxFileCount
+	if maxFileCount <= 0 {
+		maxFileCount = 1
+	}
+
+	key, err := getTempURLKey(ctx, client, containerName, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	formURL, formPath, err := tempURLPath(client, containerName, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	hmacBody := fmt.Sprintf("%s\n%s\n%d\n%d\n%d", formPath, opts.Redirect, opts.MaxFileSize, maxFileCount, expires)
+
+	sig, err := tempURLSignature(opts.Digest, key, hmacBody)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FormPOSTResult{
+		URL: formURL,
+		Fields: map[string]string{
+			"redirect":       opts.Redirect,
+			"max_file_size":  strconv.FormatInt(opts.MaxFileSize, 10),
+			"max_file_count": strconv.Itoa(maxFileCount),
+			"expires":        strconv.FormatInt(expires, 10),
+			"signature":      sig,
+		},
+	}
+
+	return result, nil
+}
+
+// tempURLExpires returns the expiry as a UNIX timestamp.
+func tempURLExpires(expires time.Time, ttl time.Duration) (int64, error) {
+	if expires.IsZero() {
+		if ttl <= 0 {
+			return 0, fmt.Errorf("either Expires or TTL must be specified")
+		}
+		expires = time.Now().Add(ttl)
+	}
+
+	return expires.Unix(), nil
+}
+
+// getTempURLKey returns the key, if it is not empty, or the temporary URL
+// key of the container or the account.
+func getTempURLKey(ctx context.Context, client *gophercloud.ServiceClient, containerName, key string) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+
+	containerHeader, err := containers.Get(ctx, client, containerName, nil).Extract()
+	if err != nil {
+		return "", fmt.Errorf("error retrieving container %s: %s", containerName, err)
+	}
+	if containerHeader.TempURLKey != "" {
+		return containerHeader.TempURLKey, nil
+	}
+
+	accountHeader, err := accounts.Get(ctx, client, nil).Extract()
+	if err != nil {
+		return "", fmt.Errorf("error retrieving account: %s", err)
+	}
+	if accountHeader.TempURLKey != "" {
+		return accountHeader.TempURLKey, nil
+	}
+
+	return "", fmt.Errorf("no temporary URL key is set for container %s or the account", containerName)
+}
+
+// tempURLPath returns the URL of an object and its unescaped path, which
+// is signed.
+func tempURLPath(client *gophercloud.ServiceClient, containerName, objectName string) (string, string, error) {
+	u, err := url.Parse(client.ResourceBaseURL())
+	if err != nil {
+		return "", "", err
+	}
+
+	objectURL := client.ResourceBaseURL() + url.PathEscape(containerName) + "/" + escapeObjectName(objectName)
+	objectPath := strings.TrimSuffix(u.Path, "/") + "/" + containerName + "/" + objectName
+
+	return objectURL, objectPath, nil
+}
+
+// tempURLSignature returns the hex encoded HMAC of the body.
+func tempURLSignature(digest, key, body string) (string, error) {
+	if digest == "" {
+		digest = DefaultTempURLDigest
+	}
+
+	h, ok := tempURLDigests[digest]
+	if !ok {
+		return "", fmt.Errorf("unsupported digest %s", digest)
+	}
+
+	mac := hmac.New(h, []byte(key))
+	mac.Write([]byte(body))
+
+	return fmt.Sprintf("%x", mac.Sum(nil)), nil
+}
@@ -0,0 +1,111 @@
+package testing
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestUploadArchivePath(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	dir := t.TempDir()
+	th.AssertNoErr(t, os.MkdirAll(filepath.Join(dir, "sub"), 0700))
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0600))
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("bb"), 0600))
+
+	modTime := time.Unix(1600000000, 0)
+	th.AssertNoErr(t, os.Chtimes(filepath.Join(dir, "a.txt"), modTime, modTime))
+
+	var uploaded int64
+	uploadOpts := &objects.UploadArchiveOpts{
+		Format: "tar.gz",
+		Path:   dir,
+		Progress: func(p objects.Progress) {
+			uploaded = p.Bytes
+		},
+	}
+
+	result, err := objects.UploadArchive(context.TODO(), client, "testContainer/folder", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, result.Success)
+	th.AssertEquals(t, 2, result.Created)
+	th.AssertEquals(t, "201 Created", result.Status)
+	if uploaded == 0 {
+		t.Error("expected the progress to be reported")
+	}
+
+	th.AssertDeepEquals(t, []string{"folder/a.txt", "folder/sub/b.txt"}, swift.names("testContainer"))
+	actual, _ := swift.get("testContainer", "folder/sub/b.txt")
+	th.AssertEquals(t, "bb", string(actual))
+	th.AssertEquals(t, "1600000000.000000", swift.containers["testContainer"]["folder/a.txt"].header.Get("X-Object-Meta-Mtime"))
+
+	// the files, which could not be created, are reported
+	swift.failures["/testContainer/folder/sub/b.txt"] = 1
+
+	result, err = objects.UploadArchive(context.TODO(), client, "testContainer/folder", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, false, result.Success)
+	th.AssertEquals(t, 1, result.Created)
+	th.AssertEquals(t, 1, len(result.Errors))
+	th.AssertEquals(t, "testContainer", result.Errors[0].Container)
+	th.AssertEquals(t, "folder/sub/b.txt", result.Errors[0].Object)
+	th.AssertEquals(t, "503 Service Unavailable", result.Errors[0].Status)
+
+	uploadOpts.Format = "tar.bz2"
+	_, err = objects.UploadArchive(context.TODO(), client, "testContainer", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestUploadArchiveContent(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, name := range []string{"one/a.txt", "two/b.txt", "invalid.txt"} {
+		th.AssertNoErr(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(name))}))
+		_, err := tw.Write([]byte(name))
+		th.AssertNoErr(t, err)
+	}
+	th.AssertNoErr(t, tw.Close())
+
+	// the top-level directories are the containers
+	uploadOpts := &objects.UploadArchiveOpts{
+		Content: bytes.NewReader(archive.Bytes()),
+	}
+
+	result, err := objects.UploadArchive(context.TODO(), client, "", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 2, result.Created)
+	th.AssertDeepEquals(t, []string{"a.txt"}, swift.names("one"))
+	th.AssertDeepEquals(t, []string{"b.txt"}, swift.names("two"))
+	th.AssertEquals(t, 1, len(result.Errors))
+	th.AssertEquals(t, "invalid.txt", result.Errors[0].Container)
+
+	uploadOpts = &objects.UploadArchiveOpts{
+		Content: strings.NewReader("not an archive"),
+		Format:  "tar.bz2",
+	}
+
+	_, err = objects.UploadArchive(context.TODO(), client, "testContainer", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
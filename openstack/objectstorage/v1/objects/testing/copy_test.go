@@ -0,0 +1,186 @@
+package testing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	o "github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestCopy(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	header := http.Header{
+		"Content-Type":      {"text/plain"},
+		"X-Object-Meta-Foo": {"foo"},
+		"X-Object-Meta-Bar": {"bar"},
+	}
+	swift.put("testContainer", "dir/test object", []byte("data"), header)
+
+	copyOpts := &objects.CopyOpts{
+		Metadata: map[string]string{"Bar": "baz"},
+	}
+
+	result, err := objects.Copy(context.TODO(), client, "testContainer", "dir/test object", "otherContainer", "copy", copyOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, result.Success)
+	th.AssertEquals(t, false, result.LargeObject)
+
+	copied := swift.containers["otherContainer"]["copy"]
+	th.AssertEquals(t, "data", string(copied.data))
+	th.AssertEquals(t, "text/plain", copied.header.Get("Content-Type"))
+	th.AssertEquals(t, "foo", copied.header.Get("X-Object-Meta-Foo"))
+	th.AssertEquals(t, "baz", copied.header.Get("X-Object-Meta-Bar"))
+
+	copyOpts = &objects.CopyOpts{
+		FreshMetadata: true,
+	}
+
+	_, err = objects.Copy(context.TODO(), client, "testContainer", "dir/test object", "otherContainer", "fresh", copyOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "", swift.containers["otherContainer"]["fresh"].header.Get("X-Object-Meta-Foo"))
+
+	_, err = objects.Move(context.TODO(), client, "testContainer", "dir/test object", "testContainer", "dir/test object", &objects.CopyOpts{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMoveSLO(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	data := strings.Repeat("0123456789", 10)
+	uploadOpts := &objects.UploadOpts{
+		Content:     strings.NewReader(data),
+		Metadata:    map[string]string{"Foo": "foo"},
+		SegmentSize: 30,
+		UseSLO:      true,
+	}
+
+	_, err := objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+	segments := swift.names("testContainer_segments")
+
+	result, err := objects.Move(context.TODO(), client, "testContainer", "testObject", "otherContainer", "moved", &objects.CopyOpts{})
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, result.LargeObject)
+
+	// the manifest refers to the same segments
+	moved := swift.containers["otherContainer"]["moved"]
+	th.AssertEquals(t, "True", moved.header.Get("X-Static-Large-Object"))
+	th.AssertEquals(t, "foo", moved.header.Get("X-Object-Meta-Foo"))
+	th.AssertEquals(t, data, string(moved.data))
+	th.AssertEquals(t, len(segments), len(moved.manifest))
+	th.AssertDeepEquals(t, segments, swift.names("testContainer_segments"))
+
+	_, ok := swift.get("testContainer", "testObject")
+	th.AssertEquals(t, false, ok)
+}
+
+func TestMoveCrossAccount(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	src := swift.account(t, fakeServer, "AUTH_src")
+	dst := swift.account(t, fakeServer, "AUTH_dst")
+
+	srcClient := fake.ServiceClient(fakeServer)
+	srcClient.ResourceBase = fakeServer.Endpoint() + "v1/AUTH_src/"
+	client := fake.ServiceClient(fakeServer)
+	client.ResourceBase = fakeServer.Endpoint() + "v1/AUTH_dst/"
+
+	src.put("srcContainer", "plain", []byte("data"), nil)
+
+	data := strings.Repeat("0123456789", 10)
+	uploadOpts := &objects.UploadOpts{
+		Content:     strings.NewReader(data),
+		SegmentSize: 30,
+		UseSLO:      true,
+	}
+
+	_, err := objects.Upload(context.TODO(), srcClient, "srcContainer", "large", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	copyOpts := &objects.CopyOpts{
+		SourceAccount: "AUTH_src",
+	}
+
+	_, err = objects.Move(context.TODO(), client, "srcContainer", "plain", "dstContainer", "plain", copyOpts)
+	th.AssertNoErr(t, err)
+
+	actual, ok := dst.get("dstContainer", "plain")
+	th.AssertEquals(t, true, ok)
+	th.AssertEquals(t, "data", string(actual))
+
+	result, err := objects.Move(context.TODO(), client, "srcContainer", "large", "dstContainer", "large", copyOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, result.LargeObject)
+
+	// the segments are copied to the destination account
+	actual, ok = dst.get("dstContainer", "large")
+	th.AssertEquals(t, true, ok)
+	th.AssertEquals(t, data, string(actual))
+	th.AssertEquals(t, 4, len(dst.names("dstContainer_segments")))
+
+	th.AssertEquals(t, 0, len(src.names("srcContainer")))
+	th.AssertEquals(t, 0, len(src.names("srcContainer_segments")))
+	th.AssertEquals(t, 0, len(swift.containers))
+}
+
+func TestCopyLargeObjects(t *testing.T) {
+	for _, useSLO := range []bool{false, true} {
+		fakeServer := th.SetupHTTP()
+		swift := HandleFakeSwift(t, fakeServer)
+		client := fake.ServiceClient(fakeServer)
+
+		data := strings.Repeat("0123456789", 10)
+		path := filepath.Join(t.TempDir(), "data")
+		th.AssertNoErr(t, os.WriteFile(path, []byte(data), 0600))
+
+		uploadOpts := &objects.UploadOpts{
+			Path:        path,
+			SegmentSize: 30,
+			UseSLO:      useSLO,
+		}
+
+		_, err := objects.Upload(context.TODO(), client, "testContainer", "a/b c", uploadOpts)
+		th.AssertNoErr(t, err)
+		segments := swift.names("testContainer_segments")
+
+		// the shared segments are not copied
+		result, err := objects.Copy(context.TODO(), client, "testContainer", "a/b c", "testContainer", "shared", &objects.CopyOpts{ShareSegments: true})
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, true, result.LargeObject)
+		th.AssertDeepEquals(t, segments, swift.names("testContainer_segments"))
+
+		result, err = objects.Copy(context.TODO(), client, "testContainer", "a/b c", "otherContainer", "copy", &objects.CopyOpts{})
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, true, result.LargeObject)
+		th.AssertEquals(t, len(segments), len(swift.names("otherContainer_segments")))
+
+		// the copy is still readable after the source has been deleted
+		_, err = objects.Delete(context.TODO(), client, "testContainer", []string{"a/b c"}, &objects.DeleteOpts{})
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, 0, len(swift.names("testContainer_segments")))
+
+		res := o.Download(context.TODO(), client, "otherContainer", "copy", nil)
+		actual, err := res.ExtractContent()
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, data, string(actual))
+
+		fakeServer.Teardown()
+	}
+}
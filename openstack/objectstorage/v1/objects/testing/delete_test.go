@@ -0,0 +1,197 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	o "github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestDeleteLargeObjects(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objectName string
+		useSLO     bool
+	}{
+		{name: "DLO", objectName: "testObject"},
+		{name: "nested DLO", objectName: "a/b c+d"},
+		{name: "SLO", objectName: "testObject", useSLO: true},
+		{name: "nested SLO", objectName: "a/b c+d", useSLO: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeServer := th.SetupHTTP()
+			defer fakeServer.Teardown()
+			swift := HandleFakeSwift(t, fakeServer)
+			client := fake.ServiceClient(fakeServer)
+
+			content := []byte(strings.Repeat("0123456789", 10))
+			path := filepath.Join(t.TempDir(), "data")
+			th.AssertNoErr(t, os.WriteFile(path, content, 0600))
+
+			uploadOpts := &objects.UploadOpts{
+				Path:        path,
+				SegmentSize: 30,
+				UseSLO:      tc.useSLO,
+			}
+
+			_, err := objects.Upload(context.TODO(), client, "testContainer", tc.objectName, uploadOpts)
+			th.AssertNoErr(t, err)
+			swift.put("testContainer", "other", []byte("other"), nil)
+			swift.put("testContainer_segments", "unrelated", []byte("unrelated"), nil)
+			th.AssertEquals(t, 5, len(swift.names("testContainer_segments")))
+
+			res := o.Download(context.TODO(), client, "testContainer", tc.objectName, nil)
+			data, err := res.ExtractContent()
+			th.AssertNoErr(t, err)
+			th.AssertDeepEquals(t, content, data)
+
+			deleteOpts := &objects.DeleteOpts{
+				Concurrency:  2,
+				NoBulkDelete: true,
+			}
+
+			results, err := objects.Delete(context.TODO(), client, "testContainer", []string{tc.objectName}, deleteOpts)
+			th.AssertNoErr(t, err)
+			th.AssertEquals(t, 5, len(results))
+			th.AssertEquals(t, "delete_object", results[0].Action)
+			for _, r := range results[1:] {
+				th.AssertEquals(t, "delete_segment", r.Action)
+				th.AssertEquals(t, "testContainer_segments", r.Container)
+				th.AssertEquals(t, true, r.Success)
+			}
+
+			th.AssertDeepEquals(t, []string{"other"}, swift.names("testContainer"))
+			th.AssertDeepEquals(t, []string{"unrelated"}, swift.names("testContainer_segments"))
+		})
+	}
+}
+
+func TestDeleteContainerBulk(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	swift.maxBulkDeletes = 2
+	client := fake.ServiceClient(fakeServer)
+
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, []byte(strings.Repeat("0123456789", 10)), 0600))
+
+	uploadOpts := &objects.UploadOpts{
+		Path:        path,
+		SegmentSize: 40,
+		UseSLO:      true,
+	}
+
+	_, err := objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		swift.put("testContainer", "dir/"+name, []byte(name), nil)
+	}
+
+	// only the objects with the prefix are deleted
+	deleteOpts := &objects.DeleteOpts{
+		DeleteContainer: true,
+		Prefix:          "dir/",
+	}
+
+	results, err := objects.Delete(context.TODO(), client, "testContainer", nil, deleteOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 3, len(results))
+	th.AssertDeepEquals(t, []string{"testObject"}, swift.names("testContainer"))
+
+	deleteOpts.Prefix = ""
+	results, err = objects.Delete(context.TODO(), client, "testContainer", nil, deleteOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 5, len(results))
+	th.AssertEquals(t, "delete_container", results[4].Action)
+	th.AssertEquals(t, 0, len(swift.names("testContainer_segments")))
+
+	_, ok := swift.containers["testContainer"]
+	th.AssertEquals(t, false, ok)
+
+	var bulkDeletes int
+	for _, r := range swift.requests {
+		if r == "POST /" {
+			bulkDeletes++
+		}
+		if strings.HasPrefix(r, "DELETE /testContainer/") || strings.HasPrefix(r, "DELETE /testContainer_segments/") {
+			t.Errorf("unexpected request: %s", r)
+		}
+	}
+	th.AssertEquals(t, 5, bulkDeletes)
+}
+
+func TestDeleteFailures(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	put := func() {
+		for _, name := range []string{"a", "b"} {
+			swift.put("testContainer", name, []byte(name), nil)
+		}
+	}
+
+	// the status of a failed deletion is reported
+	put()
+	swift.failures["/testContainer/a"] = 1
+	deleteOpts := &objects.DeleteOpts{
+		NoBulkDelete: true,
+	}
+	results, err := objects.Delete(context.TODO(), client, "testContainer", []string{"a", "b"}, deleteOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertEquals(t, false, results[0].Success)
+	th.AssertEquals(t, "503 Service Unavailable", results[0].Status)
+	th.AssertEquals(t, true, results[1].Success)
+	th.AssertDeepEquals(t, []string{"a"}, swift.names("testContainer"))
+
+	// the errors of a bulk delete are reported
+	swift.maxBulkDeletes = 10
+	put()
+	swift.failures["/testContainer/b"] = 1
+	results, err = objects.Delete(context.TODO(), client, "testContainer", []string{"a", "b"}, &objects.DeleteOpts{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertEquals(t, true, results[0].Success)
+	th.AssertEquals(t, false, results[1].Success)
+	th.AssertEquals(t, "503 Service Unavailable", results[1].Status)
+
+	// a failed bulk delete without errors fails all objects
+	put()
+	swift.bulkDeleteStatus = "500 Internal Error"
+	swift.requests = nil
+	results, err = objects.Delete(context.TODO(), client, "testContainer", []string{"a", "b"}, &objects.DeleteOpts{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, r := range results {
+		th.AssertEquals(t, false, r.Success)
+		th.AssertEquals(t, "500 Internal Error", r.Status)
+	}
+	for _, r := range swift.requests {
+		if strings.HasPrefix(r, "DELETE ") {
+			t.Errorf("unexpected request: %s", r)
+		}
+	}
+	th.AssertDeepEquals(t, []string{"a", "b"}, swift.names("testContainer"))
+
+	// a move reports the status of the failed deletion
+	swift.bulkDeleteStatus = ""
+	swift.failures["/testContainer/a"] = 1
+	_, err = objects.Move(context.TODO(), client, "testContainer", "a", "testContainer", "c", &objects.CopyOpts{})
+	if err == nil || !strings.Contains(err.Error(), "503 Service Unavailable") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
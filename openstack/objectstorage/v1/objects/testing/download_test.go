@@ -0,0 +1,225 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestDownloadResume(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	data := []byte(strings.Repeat("0123456789", 100))
+	swift.put("testContainer", "testObject", data, nil)
+
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, data[:300], 0600))
+
+	downloadOpts := &objects.DownloadOpts{
+		Checksum: true,
+		OutFile:  path,
+		Resume:   true,
+	}
+
+	// a partial file without a stored etag is downloaded again
+	results, err := objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, []string{"bytes=0-999"}, swift.ranges)
+
+	actual, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data, actual)
+
+	// a partial file with the stored etag of the object is resumed
+	etagFile := filepath.Join(filepath.Dir(path), ".data.etag")
+	th.AssertNoErr(t, os.WriteFile(path, data[:300], 0600))
+	th.AssertNoErr(t, os.WriteFile(etagFile, []byte(results[0].ETag+"\n"), 0600))
+	swift.ranges = nil
+	swift.requests = nil
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, []string{"bytes=300-999"}, swift.ranges)
+
+	// the object is not downloaded as a whole
+	var gets int
+	for _, r := range swift.requests {
+		if r == "GET /testContainer/testObject" {
+			gets++
+		}
+	}
+	th.AssertEquals(t, 1, gets)
+
+	// the stored etag is removed, once the download is complete
+	_, err = os.Stat(etagFile)
+	th.AssertEquals(t, true, os.IsNotExist(err))
+
+	// a partial file of another version of the object is downloaded again
+	th.AssertNoErr(t, os.WriteFile(path, []byte(strings.Repeat("x", 300)), 0600))
+	th.AssertNoErr(t, os.WriteFile(etagFile, []byte("d41d8cd98f00b204e9800998ecf8427e\n"), 0600))
+	swift.ranges = nil
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, []string{"bytes=0-999"}, swift.ranges)
+
+	// the object has changed since the partial download
+	th.AssertNoErr(t, os.WriteFile(path, data[:300], 0600))
+	downloadOpts.IfMatch = "d41d8cd98f00b204e9800998ecf8427e"
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	downloadOpts.IfMatch = results[0].ETag
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	// a corrupted partial file fails the verification
+	th.AssertNoErr(t, os.WriteFile(path, []byte(strings.Repeat("x", 300)), 0600))
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDownloadParallelRanges(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	swift.put("testContainer", "testObject", data, nil)
+	swift.failures["/testContainer/testObject"] = 1
+
+	path := filepath.Join(t.TempDir(), "data")
+	downloadOpts := &objects.DownloadOpts{
+		Checksum:         true,
+		OutFile:          path,
+		RangeConcurrency: 3,
+		RangeSize:        300,
+		Resume:           true,
+	}
+
+	_, err := objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// only the contiguous downloaded part is kept
+	partial, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 0, len(partial)%300)
+	th.AssertDeepEquals(t, data[:len(partial)], partial)
+
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data, actual)
+}
+
+func TestDownloadVerifySLO(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	data := []byte(strings.Repeat("0123456789", 100))
+	uploadOpts := &objects.UploadOpts{
+		Content:     strings.NewReader(string(data)),
+		SegmentSize: 300,
+		UseSLO:      true,
+	}
+
+	_, err := objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	path := filepath.Join(t.TempDir(), "data")
+	downloadOpts := &objects.DownloadOpts{
+		Checksum:         true,
+		OutFile:          path,
+		RangeConcurrency: 2,
+		RangeSize:        256,
+	}
+
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data, actual)
+}
+
+func TestDownloadSkipIdentical(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	data := []byte(strings.Repeat("0123456789", 10))
+	swift.put("testContainer", "testObject", data, nil)
+
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, data, 0600))
+
+	downloadOpts := &objects.DownloadOpts{
+		Checksum:      true,
+		OutFile:       path,
+		SkipIdentical: true,
+	}
+
+	// the identical file is left untouched
+	_, err := objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data, actual)
+
+	// a different file is downloaded again
+	th.AssertNoErr(t, os.WriteFile(path, []byte("other"), 0600))
+	_, err = objects.Download(context.TODO(), client, "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, err = os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data, actual)
+}
+
+func TestDownloadOutFileDirectory(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	swift.put("testContainer", "objectDir/testObject", []byte("data"), nil)
+
+	// the parent directory of OutFile is created, not the one of the object
+	path := filepath.Join(t.TempDir(), "a", "b", "data")
+	downloadOpts := &objects.DownloadOpts{
+		OutFile: path,
+	}
+
+	_, err := objects.Download(context.TODO(), client, "testContainer", []string{"objectDir/testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, err := os.ReadFile(path)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "data", string(actual))
+
+	_, err = os.Stat("objectDir")
+	th.AssertEquals(t, true, os.IsNotExist(err))
+}
@@ -1,8 +1,20 @@
 package testing
 
 import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -74,3 +86,704 @@ func HandleDownloadManifestSuccessfully(t *testing.T, fakeServer th.FakeServer)
 		fmt.Fprint(w, multipartManifest)
 	})
 }
+
+// copySource returns the source object of a server-side copy. The data of
+// a static large object is copied as a single object.
+func (s *fakeSwift) copySource(copyFrom, account string) (*fakeObject, bool) {
+	p, err := url.PathUnescape(copyFrom)
+	if err != nil {
+		return nil, false
+	}
+	container, object, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+
+	source := s
+	if account != "" {
+		source = s.accounts[account]
+		if source == nil {
+			return nil, false
+		}
+		if source != s {
+			source.mu.Lock()
+			defer source.mu.Unlock()
+		}
+	}
+
+	o, ok := source.containers[container][object]
+	return o, ok
+}
+
+// parseObjectManifest returns the container and the object prefix of the
+// X-Object-Manifest header, which are URL-quoted like Swift expects them.
+func parseObjectManifest(objectManifest string) (string, string, bool) {
+	container, prefix, ok := strings.Cut(objectManifest, "/")
+	if !ok {
+		return "", "", false
+	}
+
+	container, err := url.PathUnescape(container)
+	if err != nil {
+		return "", "", false
+	}
+
+	prefix, err = url.PathUnescape(prefix)
+	if err != nil {
+		return "", "", false
+	}
+
+	return container, prefix, true
+}
+
+// dloData returns the content and the etag of a dynamic large object, which
+// are derived from its segments at the time of the request.
+func (s *fakeSwift) dloData(objectManifest string) ([]byte, string) {
+	container, prefix, _ := parseObjectManifest(objectManifest)
+
+	var names []string
+	for name := range s.containers[container] {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var data []byte
+	var etags string
+	for _, name := range names {
+		segment := s.containers[container][name]
+		data = append(data, segment.data...)
+		etags += strings.Trim(segment.header.Get("Etag"), `"`)
+	}
+
+	return data, fmt.Sprintf(`"%x"`, md5.Sum([]byte(etags)))
+}
+
+// fakeObject represents an object stored by fakeSwift.
+type fakeObject struct {
+	data     []byte
+	header   http.Header
+	manifest []fakeSegment
+}
+
+// fakeSegment represents a segment of a static large object.
+type fakeSegment struct {
+	Path      string `json:"path"`
+	ETag      string `json:"etag"`
+	SizeBytes int64  `json:"size_bytes"`
+	Range     string `json:"range,omitempty"`
+
+	subSLO bool
+}
+
+// fakeSwift is an in-memory object storage, which supports the subset of
+// the Swift API used by the objects package.
+type fakeSwift struct {
+	mu         sync.Mutex
+	containers map[string]map[string]*fakeObject
+	policies   map[string]string
+	requests   []string
+
+	// failures is the number of times a PUT, a DELETE or a range GET of the
+	// object path, or a HEAD of the container path, fails with 503 Service
+	// Unavailable.
+	failures map[string]int
+
+	// ranges are the Range headers of the GET requests.
+	ranges []string
+
+	// maxBulkDeletes is the maximum number of objects deleted by a bulk
+	// delete request. If zero, the bulk-delete middleware is disabled.
+	maxBulkDeletes int
+
+	// bulkDeleteStatus is the response status of failing bulk delete
+	// requests, which delete no objects.
+	bulkDeleteStatus string
+
+	// accounts are the accounts created by account, which are shared by
+	// all accounts of the object storage.
+	accounts map[string]*fakeSwift
+}
+
+// HandleFakeSwift creates an HTTP handler at `/` on the test handler mux,
+// which serves an in-memory object storage.
+func HandleFakeSwift(t *testing.T, fakeServer th.FakeServer) *fakeSwift {
+	s := &fakeSwift{
+		containers: make(map[string]map[string]*fakeObject),
+		policies:   make(map[string]string),
+		failures:   make(map[string]int),
+		accounts:   make(map[string]*fakeSwift),
+	}
+
+	fakeServer.Mux.HandleFunc("/", s.handler(t))
+
+	return s
+}
+
+// account creates another account, which is served at `/v1/<name>/`.
+func (s *fakeSwift) account(t *testing.T, fakeServer th.FakeServer, name string) *fakeSwift {
+	a := &fakeSwift{
+		containers: make(map[string]map[string]*fakeObject),
+		policies:   make(map[string]string),
+		failures:   make(map[string]int),
+		accounts:   s.accounts,
+	}
+	s.accounts[name] = a
+
+	fakeServer.Mux.Handle("/v1/"+name+"/", http.StripPrefix("/v1/"+name, a.handler(t)))
+
+	return a
+}
+
+// handler returns the HTTP handler of the account.
+func (s *fakeSwift) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		th.TestHeader(t, r, "X-Auth-Token", fake.TokenID)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.URL.Path == "/info":
+			s.serveInfo(w)
+			return
+		case r.URL.Path == "/" && r.URL.Query().Has("bulk-delete"):
+			s.serveBulkDelete(w, r)
+			return
+		case r.Method == "PUT" && r.URL.Query().Has("extract-archive"):
+			s.serveExtractArchive(w, r)
+			return
+		}
+
+		container, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if object == "" {
+			s.serveContainer(w, r, container)
+			return
+		}
+		s.serveObject(w, r, container, object)
+	}
+}
+
+// serveInfo serves the capabilities of the object storage.
+func (s *fakeSwift) serveInfo(w http.ResponseWriter) {
+	info := map[string]any{
+		"swift": map[string]any{},
+	}
+	if s.maxBulkDeletes > 0 {
+		info["bulk_delete"] = map[string]any{
+			"max_deletes_per_request": s.maxBulkDeletes,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// serveBulkDelete deletes the objects listed in the request body.
+func (s *fakeSwift) serveBulkDelete(w http.ResponseWriter, r *http.Request) {
+	if s.maxBulkDeletes == 0 || r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	lines := strings.Fields(string(body))
+	if len(lines) > s.maxBulkDeletes {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if s.bulkDeleteStatus != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"Response Status": s.bulkDeleteStatus,
+			"Errors":          [][]string{},
+		})
+		return
+	}
+
+	var deleted, notFound int
+	errs := [][]string{}
+	for _, line := range lines {
+		p, err := url.PathUnescape(line)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if n := s.failures["/"+p]; n > 0 {
+			s.failures["/"+p] = n - 1
+			errs = append(errs, []string{"/" + line, "503 Service Unavailable"})
+			continue
+		}
+
+		container, object, _ := strings.Cut(p, "/")
+		if _, ok := s.containers[container][object]; !ok {
+			notFound++
+			continue
+		}
+		delete(s.containers[container], object)
+		deleted++
+	}
+
+	status := "200 OK"
+	if len(errs) > 0 {
+		status = "400 Bad Request"
+	}
+
+	resp := map[string]any{
+		"Response Status":  status,
+		"Errors":           errs,
+		"Number Deleted":   deleted,
+		"Number Not Found": notFound,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// serveExtractArchive creates the objects of the regular files of an
+// archive. The failures of the object paths are reported as errors.
+func (s *fakeSwift) serveExtractArchive(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	switch r.URL.Query().Get("extract-archive") {
+	case "tar":
+	case "tar.gz":
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gr
+	case "tar.bz2":
+		body = bzip2.NewReader(r.Body)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]any{
+		"Response Status": "201 Created",
+		"Response Body":   "",
+	}
+
+	var created int
+	errs := [][]string{}
+	tr := tar.NewReader(body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			resp["Response Status"] = "400 Bad Request"
+			resp["Response Body"] = "Invalid Tar File: " + err.Error()
+			break
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		p := path.Join(strings.TrimPrefix(r.URL.Path, "/"), header.Name)
+		escaped := (&url.URL{Path: "/" + p}).EscapedPath()
+		container, object, _ := strings.Cut(p, "/")
+		if object == "" {
+			errs = append(errs, []string{escaped, "400 Bad Request"})
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if n := s.failures["/"+p]; n > 0 {
+			s.failures["/"+p] = n - 1
+			errs = append(errs, []string{escaped, "503 Service Unavailable"})
+			continue
+		}
+
+		objectHeader := http.Header{}
+		for k, v := range header.PAXRecords {
+			if name, ok := strings.CutPrefix(k, "SCHILY.xattr.user.meta."); ok {
+				objectHeader.Set("X-Object-Meta-"+name, v)
+			}
+		}
+		objectHeader.Set("Etag", fmt.Sprintf("%x", md5.Sum(data)))
+		objectHeader.Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+
+		if s.containers[container] == nil {
+			s.containers[container] = make(map[string]*fakeObject)
+		}
+		s.containers[container][object] = &fakeObject{data: data, header: objectHeader}
+		created++
+	}
+
+	if len(errs) > 0 {
+		resp["Response Status"] = "400 Bad Request"
+	}
+	resp["Number Files Created"] = created
+	resp["Errors"] = errs
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// put stores an object.
+func (s *fakeSwift) put(container, object string, data []byte, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.containers[container] == nil {
+		s.containers[container] = make(map[string]*fakeObject)
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Etag", fmt.Sprintf("%x", md5.Sum(data)))
+	header.Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	s.containers[container][object] = &fakeObject{data: data, header: header}
+}
+
+// get returns the content of an object.
+func (s *fakeSwift) get(container, object string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.containers[container][object]
+	if !ok {
+		return nil, false
+	}
+	return o.data, true
+}
+
+// names returns the sorted object names of a container.
+func (s *fakeSwift) names(container string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.containers[container]))
+	for name := range s.containers[container] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// policy returns the storage policy of a container.
+func (s *fakeSwift) policy(container string) string {
+	if policy := s.policies[container]; policy != "" {
+		return policy
+	}
+	return "Policy-0"
+}
+
+func (s *fakeSwift) serveContainer(w http.ResponseWriter, r *http.Request, container string) {
+	objects, ok := s.containers[container]
+
+	switch r.Method {
+	case "PUT":
+		policy := r.Header.Get("X-Storage-Policy")
+		if ok {
+			if policy != "" && policy != s.policy(container) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		s.containers[container] = make(map[string]*fakeObject)
+		s.policies[container] = policy
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		case len(objects) > 0:
+			w.WriteHeader(http.StatusConflict)
+		default:
+			delete(s.containers, container)
+			delete(s.policies, container)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case "HEAD", "GET":
+		if n := s.failures[r.URL.Path]; n > 0 && r.Method == "HEAD" {
+			s.failures[r.URL.Path] = n - 1
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		prefix := query.Get("prefix")
+		delimiter := query.Get("delimiter")
+		marker := query.Get("marker")
+
+		names := make([]string, 0, len(objects))
+		for name := range objects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		type entry struct {
+			Name         string `json:"name,omitempty"`
+			Subdir       string `json:"subdir,omitempty"`
+			Bytes        int64  `json:"bytes"`
+			Hash         string `json:"hash,omitempty"`
+			ContentType  string `json:"content_type,omitempty"`
+			LastModified string `json:"last_modified,omitempty"`
+		}
+		entries := []entry{}
+		subdirs := map[string]bool{}
+		for _, name := range names {
+			if !strings.HasPrefix(name, prefix) || name <= marker {
+				continue
+			}
+
+			if delimiter != "" {
+				if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+					subdir := name[:len(prefix)+i+len(delimiter)]
+					if !subdirs[subdir] && subdir > marker {
+						subdirs[subdir] = true
+						entries = append(entries, entry{Subdir: subdir})
+					}
+					continue
+				}
+			}
+
+			o := objects[name]
+			entries = append(entries, entry{
+				Name:         name,
+				Bytes:        int64(len(o.data)),
+				Hash:         strings.Trim(o.header.Get("Etag"), `"`),
+				ContentType:  o.header.Get("Content-Type"),
+				LastModified: "2018-04-22T01:34:00.000000",
+			})
+		}
+
+		w.Header().Set("X-Container-Object-Count", strconv.Itoa(len(objects)))
+		w.Header().Set("X-Storage-Policy", s.policy(container))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(entries)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeSwift) serveObject(w http.ResponseWriter, r *http.Request, container, object string) {
+	objects, ok := s.containers[container]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	o, exists := objects[object]
+	query := r.URL.Query()
+
+	switch r.Method {
+	case "PUT":
+		if n := s.failures[r.URL.Path]; n > 0 {
+			s.failures[r.URL.Path] = n - 1
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		header := http.Header{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Object-Meta-") || k == "Content-Type" || k == "X-Object-Manifest" {
+				header[k] = v
+			}
+		}
+
+		if v := r.Header.Get("X-Copy-From"); v != "" {
+			source, ok := s.copySource(v, r.Header.Get("X-Copy-From-Account"))
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			data = source.data
+			if r.Header.Get("X-Fresh-Metadata") != "true" {
+				for k, v := range source.header {
+					if _, ok := header[k]; !ok && (strings.HasPrefix(k, "X-Object-Meta-") || k == "Content-Type") {
+						header[k] = v
+					}
+				}
+			}
+		}
+
+		if v := header.Get("X-Object-Manifest"); v != "" {
+			if _, _, ok := parseObjectManifest(v); !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		o = &fakeObject{header: header}
+		if query.Get("multipart-manifest") == "put" {
+			if err := json.Unmarshal(data, &o.manifest); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			var etags string
+			for i, seg := range o.manifest {
+				segContainer, segObject, _ := strings.Cut(strings.TrimPrefix(seg.Path, "/"), "/")
+				segment, ok := s.containers[segContainer][segObject]
+				if !ok || int64(len(segment.data)) != seg.SizeBytes {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				o.manifest[i].subSLO = segment.manifest != nil
+
+				etag := strings.Trim(segment.header.Get("Etag"), `"`)
+				if seg.Range == "" {
+					o.data = append(o.data, segment.data...)
+					etags += etag
+					continue
+				}
+
+				var start, end int
+				if _, err := fmt.Sscanf(seg.Range, "%d-%d", &start, &end); err != nil || start > end || end >= len(segment.data) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				o.data = append(o.data, segment.data[start:end+1]...)
+				etags += etag + ":" + seg.Range + ";"
+			}
+			header.Set("X-Static-Large-Object", "True")
+			header.Set("Etag", fmt.Sprintf(`"%x"`, md5.Sum([]byte(etags))))
+		} else {
+			etag := fmt.Sprintf("%x", md5.Sum(data))
+			if v := r.Header.Get("Etag"); v != "" && v != etag {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			o.data = data
+			header.Set("Etag", etag)
+		}
+		header.Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+
+		objects[object] = o
+		w.Header().Set("Etag", header.Get("Etag"))
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if n := s.failures[r.URL.Path]; n > 0 {
+			s.failures[r.URL.Path] = n - 1
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(objects, object)
+		w.WriteHeader(http.StatusNoContent)
+	case "HEAD", "GET":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		data := o.data
+		header := o.header
+		if v := header.Get("X-Object-Manifest"); v != "" && query.Get("multipart-manifest") != "get" {
+			var etag string
+			data, etag = s.dloData(v)
+			header = header.Clone()
+			header.Set("Etag", etag)
+		}
+
+		if o.manifest != nil && query.Get("multipart-manifest") == "get" {
+			type segment struct {
+				Name         string `json:"name"`
+				Hash         string `json:"hash"`
+				Bytes        int64  `json:"bytes"`
+				ContentType  string `json:"content_type"`
+				LastModified string `json:"last_modified"`
+				Range        string `json:"range,omitempty"`
+				SubSLO       bool   `json:"sub_slo,omitempty"`
+			}
+			manifest := make([]segment, 0, len(o.manifest))
+			for _, seg := range o.manifest {
+				manifest = append(manifest, segment{
+					Name:         seg.Path,
+					Hash:         seg.ETag,
+					Bytes:        seg.SizeBytes,
+					ContentType:  "application/swiftclient-segment",
+					LastModified: "2018-04-22T01:34:00.000000",
+					Range:        seg.Range,
+					SubSLO:       seg.subSLO,
+				})
+			}
+			data, _ = json.Marshal(manifest)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+
+		if v := r.Header.Get("If-Match"); v != "" && strings.Trim(v, `"`) != strings.Trim(header.Get("Etag"), `"`) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		if v := r.Header.Get("If-None-Match"); v != "" && strings.Trim(v, `"`) == strings.Trim(header.Get("Etag"), `"`) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		status := http.StatusOK
+		if v := r.Header.Get("Range"); v != "" && r.Method == "GET" {
+			s.ranges = append(s.ranges, v)
+
+			if n := s.failures[r.URL.Path]; n > 0 {
+				s.failures[r.URL.Path] = n - 1
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			var start, end int
+			if _, err := fmt.Sscanf(v, "bytes=%d-%d", &start, &end); err != nil || start > end || start >= len(data) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			end = min(end, len(data)-1)
+
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == "GET" {
+			_, _ = w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,116 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestContainerFS(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	swift.put("testContainer", "a.txt", []byte("a"), http.Header{"X-Object-Meta-Mtime": {"1600000000.500000"}})
+	swift.put("testContainer", "dir", nil, http.Header{"Content-Type": {"application/directory"}})
+	swift.put("testContainer", "dir/b.txt", []byte("bb"), nil)
+	swift.put("testContainer", "dir/sub/c.txt", []byte("ccc"), nil)
+	swift.put("testContainer", "pseudo/d.txt", []byte("dddd"), nil)
+	swift.put("testContainer", "marker/", nil, nil)
+
+	fsys := objects.NewContainerFS(context.TODO(), fake.ServiceClient(fakeServer), "testContainer")
+
+	th.AssertNoErr(t, fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "pseudo/d.txt", "marker"))
+
+	var walked []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, []string{".", "a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt", "marker", "pseudo", "pseudo/d.txt"}, walked)
+
+	data, err := fs.ReadFile(fsys, "dir/sub/c.txt")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "ccc", string(data))
+
+	info, err := fs.Stat(fsys, "a.txt")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, int64(1), info.Size())
+	th.AssertEquals(t, time.Unix(1600000000, 500000000).UTC(), info.ModTime().UTC())
+
+	for _, name := range []string{"dir", "pseudo", "marker"} {
+		info, err := fs.Stat(fsys, name)
+		th.AssertNoErr(t, err)
+		th.AssertEquals(t, true, info.IsDir())
+	}
+
+	_, err = fs.Stat(fsys, "missing")
+	th.AssertEquals(t, true, errors.Is(err, fs.ErrNotExist))
+
+	_, err = fsys.Open("../a.txt")
+	th.AssertEquals(t, true, errors.Is(err, fs.ErrInvalid))
+
+	// the pseudo-folder of the container name is the root directory
+	sub := objects.NewContainerFS(context.TODO(), fake.ServiceClient(fakeServer), "testContainer/dir")
+	entries, err := fs.ReadDir(sub, ".")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 2, len(entries))
+	th.AssertEquals(t, "b.txt", entries[0].Name())
+	th.AssertEquals(t, "sub", entries[1].Name())
+	th.AssertEquals(t, true, entries[1].IsDir())
+}
+
+func TestContainerFSReadDirFile(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	swift.put("testContainer", "file.txt", []byte("a"), nil)
+
+	fsys := objects.NewContainerFS(context.TODO(), fake.ServiceClient(fakeServer), "testContainer")
+
+	entries, err := fsys.ReadDir("file.txt")
+	th.AssertEquals(t, 0, len(entries))
+	th.AssertEquals(t, true, errors.Is(err, syscall.ENOTDIR))
+}
+
+func TestContainerFSObjectAndPseudoDir(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	// the object takes precedence over the pseudo-directory
+	swift.put("testContainer", "x", []byte("x"), nil)
+	swift.put("testContainer", "x/y.txt", []byte("y"), nil)
+
+	fsys := objects.NewContainerFS(context.TODO(), fake.ServiceClient(fakeServer), "testContainer")
+
+	f, err := fsys.Open("x")
+	th.AssertNoErr(t, err)
+	info, err := f.Stat()
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, false, info.IsDir())
+	th.AssertNoErr(t, f.Close())
+
+	entries, err := fsys.ReadDir(".")
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 1, len(entries))
+	th.AssertEquals(t, "x", entries[0].Name())
+	th.AssertEquals(t, false, entries[0].IsDir())
+
+	_, err = fsys.ReadDir("x")
+	th.AssertEquals(t, true, errors.Is(err, syscall.ENOTDIR))
+}
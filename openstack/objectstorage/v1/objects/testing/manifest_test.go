@@ -1,7 +1,13 @@
 package testing
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	o "github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
@@ -71,3 +77,115 @@ func TestChunkData(t *testing.T) {
 	th.AssertNoErr(t, err)
 	th.AssertDeepEquals(t, actualChunkData, expectedMultipartManifest)
 }
+
+// createSLO creates a static large object of the specified segments.
+func createSLO(t *testing.T, fakeServer th.FakeServer, swift *fakeSwift, container, object string, segments []fakeSegment) {
+	swift.mu.Lock()
+	if swift.containers[container] == nil {
+		swift.containers[container] = make(map[string]*fakeObject)
+	}
+	for i, seg := range segments {
+		segContainer, segObject, _ := strings.Cut(strings.TrimPrefix(seg.Path, "/"), "/")
+		segment := swift.containers[segContainer][segObject]
+		segments[i].ETag = strings.Trim(segment.header.Get("Etag"), `"`)
+		segments[i].SizeBytes = int64(len(segment.data))
+	}
+	swift.mu.Unlock()
+
+	body, err := json.Marshal(segments)
+	th.AssertNoErr(t, err)
+
+	createOpts := o.CreateOpts{
+		Content:           bytes.NewReader(body),
+		MultipartManifest: "put",
+	}
+	res := o.Create(context.TODO(), fake.ServiceClient(fakeServer), container, object, createOpts)
+	th.AssertNoErr(t, res.Err)
+}
+
+func TestGetManifestSubSLO(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	swift.put("seg", "a", []byte("aaaaaaaaaa"), nil)
+	swift.put("seg", "b", []byte("bbbbbbbbbb"), nil)
+	swift.put("seg", "c", []byte("cccccccccc"), nil)
+	createSLO(t, fakeServer, swift, "seg", "sub", []fakeSegment{{Path: "/seg/a"}, {Path: "/seg/b"}})
+	createSLO(t, fakeServer, swift, "testContainer", "nested", []fakeSegment{{Path: "/seg/sub"}, {Path: "/seg/c"}})
+	createSLO(t, fakeServer, swift, "testContainer", "ranged", []fakeSegment{{Path: "/seg/sub", Range: "5-14"}, {Path: "/seg/c", Range: "0-9"}})
+
+	gmo := objects.GetManifestOpts{
+		ContainerName:     "testContainer",
+		ObjectName:        "nested",
+		StaticLargeObject: true,
+	}
+
+	manifest, err := objects.GetManifest(context.TODO(), client, gmo)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 3, len(manifest))
+	for i, name := range []string{"/seg/a", "/seg/b", "/seg/c"} {
+		th.AssertEquals(t, name, manifest[i].Name)
+		th.AssertEquals(t, int64(10), manifest[i].Bytes)
+		th.AssertEquals(t, "", manifest[i].Range)
+	}
+
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, []byte("aaaaaaaaaabbbbbbbbbbcccccccccc"), 0600))
+
+	identical, err := objects.IsIdentical(manifest, path)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, identical)
+
+	gmo.ObjectName = "ranged"
+	manifest, err = objects.GetManifest(context.TODO(), client, gmo)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 3, len(manifest))
+	th.AssertEquals(t, "5-9", manifest[0].Range)
+	th.AssertEquals(t, int64(5), manifest[0].Bytes)
+	th.AssertEquals(t, "0-4", manifest[1].Range)
+	th.AssertEquals(t, int64(5), manifest[1].Bytes)
+	th.AssertEquals(t, "", manifest[2].Range)
+	th.AssertEquals(t, int64(10), manifest[2].Bytes)
+
+	// the checksums of ranged segments cannot be compared
+	th.AssertNoErr(t, os.WriteFile(path, []byte("aaaaabbbbbcccccccccc"), 0600))
+	identical, err = objects.IsIdentical(manifest, path)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, false, identical)
+
+	results, err := objects.Delete(context.TODO(), client, "testContainer", []string{"nested"}, &objects.DeleteOpts{})
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 5, len(results))
+	th.AssertDeepEquals(t, []string{"ranged"}, swift.names("testContainer"))
+	th.AssertEquals(t, 0, len(swift.names("seg")))
+}
+
+func TestGetManifestMaxDepth(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	swift.put("seg", "0", []byte("data"), nil)
+	for i := 1; i <= 11; i++ {
+		createSLO(t, fakeServer, swift, "seg", strconv.Itoa(i), []fakeSegment{{Path: "/seg/" + strconv.Itoa(i-1)}})
+	}
+
+	gmo := objects.GetManifestOpts{
+		ContainerName:     "seg",
+		ObjectName:        "10",
+		StaticLargeObject: true,
+	}
+
+	manifest, err := objects.GetManifest(context.TODO(), fake.ServiceClient(fakeServer), gmo)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 1, len(manifest))
+	th.AssertEquals(t, "/seg/0", manifest[0].Name)
+
+	gmo.ObjectName = "11"
+	_, err = objects.GetManifest(context.TODO(), fake.ServiceClient(fakeServer), gmo)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,118 @@
+package testing
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestObjectReader(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	swift.put("testContainer", "testObject", data, nil)
+
+	opts := &objects.ObjectReaderOpts{
+		BlockSize: 100,
+		ReadAhead: 2,
+	}
+
+	r, err := objects.OpenObject(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", "testObject", opts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, int64(1000), r.Size())
+
+	buf := make([]byte, 150)
+	n, err := r.ReadAt(buf, 950)
+	th.AssertEquals(t, io.EOF, err)
+	th.AssertDeepEquals(t, data[950:], buf[:n])
+
+	n, err = r.ReadAt(buf, 120)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data[120:270], buf[:n])
+
+	_, err = r.Seek(-500, io.SeekEnd)
+	th.AssertNoErr(t, err)
+	actual, err := io.ReadAll(r)
+	th.AssertNoErr(t, err)
+	th.AssertDeepEquals(t, data[500:], actual)
+
+	// the cached blocks are not fetched again
+	ranges := len(swift.ranges)
+	_, err = r.ReadAt(buf[:50], 900)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, ranges, len(swift.ranges))
+
+	th.AssertNoErr(t, r.Close())
+	_, err = r.ReadAt(buf, 0)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestObjectReaderSLO(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	// zip readers need random access to the central directory at the end
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		w, err := zw.Create(name)
+		th.AssertNoErr(t, err)
+		_, err = io.WriteString(w, strings.Repeat(name, 200))
+		th.AssertNoErr(t, err)
+	}
+	th.AssertNoErr(t, zw.Close())
+
+	uploadOpts := &objects.UploadOpts{
+		// hide the io.Seeker implementation to upload segments
+		Content:     io.MultiReader(bytes.NewReader(archive.Bytes())),
+		SegmentSize: 128,
+		UseSLO:      true,
+	}
+
+	_, err := objects.Upload(context.TODO(), client, "testContainer", "archive.zip", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	opts := &objects.ObjectReaderOpts{
+		BlockSize: 100,
+	}
+
+	r, err := objects.OpenObject(context.TODO(), client, "testContainer", "archive.zip", opts)
+	th.AssertNoErr(t, err)
+	defer r.Close()
+
+	zr, err := zip.NewReader(r, r.Size())
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 3, len(zr.File))
+
+	f, err := zr.Open("b.txt")
+	th.AssertNoErr(t, err)
+	actual, err := io.ReadAll(f)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, strings.Repeat("b.txt", 200), string(actual))
+
+	// the data is read from the segments only
+	for _, r := range swift.requests {
+		if r == "GET /testContainer/archive.zip" {
+			continue
+		}
+		if strings.HasPrefix(r, "GET /testContainer/") {
+			t.Errorf("unexpected request: %s", r)
+		}
+	}
+}
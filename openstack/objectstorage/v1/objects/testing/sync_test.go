@@ -0,0 +1,141 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestSyncDirectory(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	src := t.TempDir()
+	th.AssertNoErr(t, os.MkdirAll(filepath.Join(src, "a", "b"), 0755))
+	th.AssertNoErr(t, os.MkdirAll(filepath.Join(src, "empty"), 0755))
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(src, "top.txt"), []byte("top"), 0600))
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(src, "a", "b", "deep.txt"), []byte("deep"), 0600))
+
+	swift.put("testContainer", "backup/stale.txt", []byte("stale"), nil)
+	swift.put("testContainer", "other.txt", []byte("other"), nil)
+
+	opts := &objects.SyncOpts{
+		Changed:     true,
+		Concurrency: 2,
+		Delete:      true,
+		DryRun:      true,
+	}
+
+	results, err := objects.UploadDirectory(context.TODO(), client, "testContainer/backup", src, opts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 4, len(results))
+	for _, r := range results {
+		th.AssertEquals(t, "dry-run", r.Status)
+	}
+	th.AssertDeepEquals(t, []string{"backup/stale.txt", "other.txt"}, swift.names("testContainer"))
+
+	opts.DryRun = false
+	swift.requests = nil
+	results, err = objects.UploadDirectory(context.TODO(), client, "testContainer/backup", src, opts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 4, len(results))
+	th.AssertEquals(t, "delete_object", results[3].Action)
+	th.AssertEquals(t, "deleted", results[3].Status)
+	th.AssertDeepEquals(t, []string{"backup/a/b/deep.txt", "backup/empty", "backup/top.txt", "other.txt"}, swift.names("testContainer"))
+
+	// the container is created once
+	var containerRequests int
+	for _, r := range swift.requests {
+		if r == "HEAD /testContainer" || r == "PUT /testContainer" {
+			containerRequests++
+		}
+	}
+	th.AssertEquals(t, 1, containerRequests)
+
+	// unchanged files are skipped
+	results, err = objects.UploadDirectory(context.TODO(), client, "testContainer/backup", src, opts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "skip-changed", results[0].Status)
+
+	dst := t.TempDir()
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(dst, "extra.txt"), []byte("extra"), 0600))
+
+	opts.Checksum = true
+	swift.requests = nil
+	results, err = objects.DownloadDirectory(context.TODO(), client, "testContainer/backup", dst, opts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, 4, len(results))
+
+	// the object is retrieved once before it is downloaded
+	var headRequests int
+	for _, r := range swift.requests {
+		if r == "HEAD /testContainer/backup/top.txt" {
+			headRequests++
+		}
+	}
+	th.AssertEquals(t, 1, headRequests)
+
+	data, err := os.ReadFile(filepath.Join(dst, "a", "b", "deep.txt"))
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "deep", string(data))
+
+	info, err := os.Stat(filepath.Join(dst, "empty"))
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, info.IsDir())
+
+	_, err = os.Stat(filepath.Join(dst, "extra.txt"))
+	th.AssertEquals(t, true, os.IsNotExist(err))
+
+	// the mtime has been restored, so the files are unchanged
+	results, err = objects.DownloadDirectory(context.TODO(), client, "testContainer/backup", dst, opts)
+	th.AssertNoErr(t, err)
+	for _, r := range results {
+		if r.Action == "download_object" {
+			th.AssertEquals(t, "skip-changed", r.Status)
+		}
+	}
+}
+
+func TestSyncDirectoryDeleteFailures(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	src := t.TempDir()
+	th.AssertNoErr(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0600))
+
+	swift.put("testContainer", "stale.txt", []byte("stale"), nil)
+	swift.failures["/testContainer/stale.txt"] = 1
+
+	opts := &objects.SyncOpts{
+		Delete: true,
+	}
+
+	results, err := objects.UploadDirectory(context.TODO(), client, "testContainer", src, opts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertEquals(t, 2, len(results))
+	th.AssertEquals(t, "delete_object", results[1].Action)
+	th.AssertEquals(t, "503 Service Unavailable", results[1].Status)
+	th.AssertEquals(t, false, results[1].Success)
+	if results[1].Error == nil {
+		t.Fatal("expected an error of the result")
+	}
+
+	// the segments of the uploaded objects are not extraneous objects
+	opts.SegmentContainer = "testContainer"
+	_, err = objects.UploadDirectory(context.TODO(), client, "testContainer", src, opts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertDeepEquals(t, []string{"a.txt", "stale.txt"}, swift.names("testContainer"))
+}
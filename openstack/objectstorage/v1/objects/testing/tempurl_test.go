@@ -0,0 +1,112 @@
+package testing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestTempURL(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	client := fake.ServiceClient(fakeServer)
+
+	fakeServer.Mux.HandleFunc("/testContainer", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "HEAD")
+		w.Header().Set("X-Container-Meta-Temp-Url-Key", "secret")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	expires := time.Unix(1700000000, 0)
+
+	opts := &objects.TempURLOpts{
+		Expires: expires,
+	}
+
+	actual, err := objects.TempURL(context.TODO(), client, "testContainer", "dir/test object", opts)
+	th.AssertNoErr(t, err)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("GET\n1700000000\n/testContainer/dir/test object"))
+	expected := client.Endpoint + "testContainer/dir/test%20object?temp_url_expires=1700000000&temp_url_sig=" + fmt.Sprintf("%x", mac.Sum(nil))
+	th.AssertEquals(t, expected, actual)
+
+	opts = &objects.TempURLOpts{
+		Digest:  "sha1",
+		Expires: expires,
+		IPRange: "10.0.0.0/8",
+		Key:     "other",
+		Method:  "PUT",
+		Prefix:  true,
+	}
+
+	actual, err = objects.TempURL(context.TODO(), client, "testContainer", "dir/", opts)
+	th.AssertNoErr(t, err)
+
+	u, err := url.Parse(actual)
+	th.AssertNoErr(t, err)
+	mac = hmac.New(sha1.New, []byte("other"))
+	mac.Write([]byte("ip=10.0.0.0/8\nPUT\n1700000000\nprefix:/testContainer/dir/"))
+	th.AssertEquals(t, fmt.Sprintf("%x", mac.Sum(nil)), u.Query().Get("temp_url_sig"))
+	th.AssertEquals(t, "dir/", u.Query().Get("temp_url_prefix"))
+	th.AssertEquals(t, "10.0.0.0/8", u.Query().Get("temp_url_ip_range"))
+
+	opts.Digest = "md5"
+	_, err = objects.TempURL(context.TODO(), client, "testContainer", "dir/", opts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestFormPOST(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	client := fake.ServiceClient(fakeServer)
+
+	fakeServer.Mux.HandleFunc("/testContainer", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	fakeServer.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "HEAD")
+		w.Header().Set("X-Account-Meta-Temp-Url-Key", "secret")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	opts := &objects.FormPOSTOpts{
+		Digest:       "sha512",
+		Expires:      time.Unix(1700000000, 0),
+		MaxFileCount: 5,
+		MaxFileSize:  1048576,
+		Prefix:       "uploads/",
+		Redirect:     "https://example.com/done",
+	}
+
+	actual, err := objects.FormPOST(context.TODO(), client, "testContainer", opts)
+	th.AssertNoErr(t, err)
+
+	mac := hmac.New(sha512.New, []byte("secret"))
+	mac.Write([]byte("/testContainer/uploads/\nhttps://example.com/done\n1048576\n5\n1700000000"))
+
+	expected := &objects.FormPOSTResult{
+		Fields: map[string]string{
+			"redirect":       "https://example.com/done",
+			"max_file_size":  "1048576",
+			"max_file_count": "5",
+			"expires":        "1700000000",
+			"signature":      fmt.Sprintf("%x", mac.Sum(nil)),
+		},
+		URL: client.Endpoint + "testContainer/uploads/",
+	}
+	th.AssertDeepEquals(t, expected, actual)
+}
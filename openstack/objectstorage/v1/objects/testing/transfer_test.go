@@ -0,0 +1,120 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestUploadProgress(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	data := []byte(strings.Repeat("0123456789", 100))
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, data, 0600))
+
+	info, err := os.Stat(path)
+	th.AssertNoErr(t, err)
+	swift.failures["/testContainer_segments/testObject/slo/"+mtime(info)+"/1000/300/00000001"] = 1
+
+	var mu sync.Mutex
+	var last objects.Progress
+	segments := make(map[int]int64)
+
+	uploadOpts := &objects.UploadOpts{
+		Path:               path,
+		SegmentSize:        300,
+		SegmentConcurrency: 2,
+		SegmentRetries:     1,
+		UseSLO:             true,
+		Progress: func(p objects.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			last = p
+			segments[p.Segment] = p.SegmentBytes
+		},
+	}
+
+	_, err = objects.Upload(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	th.AssertEquals(t, int64(1000), last.Bytes)
+	th.AssertEquals(t, int64(1000), last.Total)
+	th.AssertEquals(t, "testObject", last.Object)
+	th.AssertDeepEquals(t, map[int]int64{0: 300, 1: 300, 2: 300, 3: 100}, segments)
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	limiter := &objects.BandwidthLimiter{
+		BytesPerSecond: 2000,
+	}
+
+	// the first second of bytes is not throttled
+	start := time.Now()
+	for _, name := range []string{"a", "b"} {
+		uploadOpts := &objects.UploadOpts{
+			BandwidthLimiter: limiter,
+			Content:          strings.NewReader(strings.Repeat("x", 1500)),
+		}
+
+		_, err := objects.Upload(context.TODO(), client, "testContainer", name, uploadOpts)
+		th.AssertNoErr(t, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected the uploads to be throttled, but they took %s", elapsed)
+	}
+	th.AssertEquals(t, 2, len(swift.names("testContainer")))
+
+	// a canceled context stops a throttled transfer
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+
+	uploadOpts := &objects.UploadOpts{
+		BandwidthLimiter: limiter,
+		Content:          strings.NewReader(strings.Repeat("x", 10000)),
+	}
+
+	_, err := objects.Upload(ctx, client, "testContainer", "c", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	swift.put("testContainer", "testObject", []byte(strings.Repeat("0123456789", 10000)), nil)
+
+	var last objects.Progress
+	downloadOpts := &objects.DownloadOpts{
+		OutFile: filepath.Join(t.TempDir(), "data"),
+		Progress: func(p objects.Progress) {
+			last = p
+		},
+	}
+
+	_, err := objects.Download(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", []string{"testObject"}, downloadOpts)
+	th.AssertNoErr(t, err)
+
+	th.AssertEquals(t, -1, last.Segment)
+	th.AssertEquals(t, int64(100000), last.Bytes)
+	th.AssertEquals(t, int64(100000), last.Total)
+}
@@ -0,0 +1,194 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	th "github.com/gophercloud/gophercloud/v2/testhelper"
+	fake "github.com/gophercloud/gophercloud/v2/testhelper/client"
+	"github.com/gophercloud/utils/v2/openstack/objectstorage/v1/objects"
+)
+
+func TestUploadConcurrentSegments(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	data := []byte(strings.Repeat("0123456789", 100))
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, data, 0600))
+
+	info, err := os.Stat(path)
+	th.AssertNoErr(t, err)
+	failing := "/testContainer_segments/testObject/slo/" + mtime(info) + "/1000/64/00000003"
+	swift.failures[failing] = 1
+
+	uploadOpts := &objects.UploadOpts{
+		Checksum:           true,
+		Path:               path,
+		SegmentSize:        64,
+		SegmentConcurrency: 4,
+		SegmentRetries:     1,
+		UseSLO:             true,
+	}
+
+	result, err := objects.Upload(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, true, result.LargeObject)
+
+	actual, ok := swift.get("testContainer", "testObject")
+	th.AssertEquals(t, true, ok)
+	th.AssertDeepEquals(t, data, actual)
+	th.AssertEquals(t, 16, len(swift.names("testContainer_segments")))
+	th.AssertEquals(t, 0, swift.failures[failing])
+}
+
+func TestUploadConcurrentStreamingSegments(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	data := []byte(strings.Repeat("abcdefghij", 100))
+
+	uploadOpts := &objects.UploadOpts{
+		// hide the io.Seeker implementation to test streaming
+		Content:            io.MultiReader(bytes.NewReader(data)),
+		SegmentSize:        100,
+		SegmentConcurrency: 3,
+		UseSLO:             true,
+	}
+
+	_, err := objects.Upload(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, ok := swift.get("testContainer", "testObject")
+	th.AssertEquals(t, true, ok)
+	th.AssertDeepEquals(t, data, actual)
+	th.AssertEquals(t, 10, len(swift.names("testContainer_segments")))
+}
+
+// mtime formats the modification time of a file the same way as Upload.
+func mtime(info os.FileInfo) string {
+	return fmt.Sprintf("%.6f", float64(info.ModTime().UnixNano())/1000000000)
+}
+
+func TestUploadResume(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "data")
+	th.AssertNoErr(t, os.WriteFile(path, data, 0600))
+
+	info, err := os.Stat(path)
+	th.AssertNoErr(t, err)
+
+	// a previous upload has failed after three segments, the second one is
+	// corrupted
+	prefix := "testObject/slo/" + mtime(info) + "/1000/400/"
+	swift.put("testContainer_segments", prefix+"00000000", data[:400], nil)
+	swift.put("testContainer_segments", prefix+"00000001", data[:400], nil)
+	swift.put("testContainer_segments", prefix+"00000002", data[800:], nil)
+
+	uploadOpts := &objects.UploadOpts{
+		Path:        path,
+		SegmentSize: 400,
+		UseSLO:      true,
+		Resume:      true,
+	}
+
+	_, err = objects.Upload(context.TODO(), fake.ServiceClient(fakeServer), "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+
+	actual, ok := swift.get("testContainer", "testObject")
+	th.AssertEquals(t, true, ok)
+	th.AssertDeepEquals(t, data, actual)
+
+	var puts []string
+	for _, r := range swift.requests {
+		if strings.HasPrefix(r, "PUT /testContainer_segments/") {
+			puts = append(puts, r)
+		}
+	}
+	th.AssertDeepEquals(t, []string{"PUT /testContainer_segments/" + prefix + "00000001"}, puts)
+}
+
+func TestUploadStoragePolicy(t *testing.T) {
+	fakeServer := th.SetupHTTP()
+	defer fakeServer.Teardown()
+	swift := HandleFakeSwift(t, fakeServer)
+	client := fake.ServiceClient(fakeServer)
+
+	uploadOpts := &objects.UploadOpts{
+		Content:       strings.NewReader(strings.Repeat("0123456789", 10)),
+		SegmentSize:   30,
+		StoragePolicy: "gold",
+		UseSLO:        true,
+	}
+
+	_, err := objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "gold", swift.policy("testContainer"))
+	th.AssertEquals(t, "gold", swift.policy("testContainer_segments"))
+
+	// the segment container inherits the policy of an existing container
+	uploadOpts = &objects.UploadOpts{
+		Content:          strings.NewReader("data"),
+		SegmentContainer: "otherSegments",
+		SegmentSize:      30,
+		UseSLO:           true,
+	}
+
+	_, err = objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	th.AssertNoErr(t, err)
+	th.AssertEquals(t, "gold", swift.policy("otherSegments"))
+
+	// the policy of an existing container must match
+	uploadOpts = &objects.UploadOpts{
+		Content:       strings.NewReader("data"),
+		StoragePolicy: "silver",
+	}
+
+	_, err = objects.Upload(context.TODO(), client, "testContainer", "testObject", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	uploadOpts = &objects.UploadOpts{
+		Content:          strings.NewReader("data"),
+		SegmentContainer: "otherSegments",
+		SegmentSize:      30,
+		StoragePolicy:    "silver",
+	}
+
+	_, err = objects.Upload(context.TODO(), client, "otherContainer", "testObject", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	th.AssertEquals(t, "silver", swift.policy("otherContainer"))
+
+	// the policy is not skipped, when the HEAD of the container fails
+	swift.put("failingContainer", "other", []byte("other"), nil)
+	swift.failures["/failingContainer"] = 1
+	uploadOpts = &objects.UploadOpts{
+		Content:       strings.NewReader("data"),
+		StoragePolicy: "silver",
+	}
+
+	_, err = objects.Upload(context.TODO(), client, "failingContainer", "testObject", uploadOpts)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	_, ok := swift.get("failingContainer", "testObject")
+	th.AssertEquals(t, false, ok)
+}
@@ -0,0 +1,242 @@
+package objects
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Progress represents the progress of an object transfer.
+type Progress struct {
+	Container string
+	Object    string
+
+	// Segment is the index of the transferred segment, or -1, when the
+	// object is not transferred in segments.
+	Segment int
+
+	// SegmentBytes is the number of bytes of the segment transferred so
+	// far.
+	SegmentBytes int64
+
+	// Bytes is the number of bytes of the object transferred so far. It
+	// decreases, when a failed segment transfer is retried.
+	Bytes int64
+
+	// Total is the size of the object, or -1, when it is not known.
+	Total int64
+}
+
+// ProgressFunc is called, when a part of an object has been transferred.
+// The calls for a single object are serialized, but the calls for different
+// objects may be concurrent.
+type ProgressFunc func(Progress)
+
+// ProgressChan returns a ProgressFunc, which sends the progress to a
+// channel. The transfer is blocked, while the channel is full.
+func ProgressChan(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		ch <- p
+	}
+}
+
+// BandwidthLimiter limits the bandwidth of object transfers. A single
+// BandwidthLimiter can be shared by concurrent uploads and downloads to
+// limit their total bandwidth. A BandwidthLimiter must not be copied after
+// first use.
+type BandwidthLimiter struct {
+	// BytesPerSecond is the maximum number of bytes transferred per second.
+	// Zero means no limit.
+	BytesPerSecond int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// chunkSize returns the maximum size of a single read, so that transfers
+// are throttled smoothly.
+func (l *BandwidthLimiter) chunkSize() int {
+	if l == nil || l.BytesPerSecond <= 0 {
+		return 0
+	}
+	return int(max(l.BytesPerSecond/10, 1))
+}
+
+// wait blocks until n bytes may be transferred. The bucket holds the bytes
+// of up to one second.
+func (l *BandwidthLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || l.BytesPerSecond <= 0 {
+		return nil
+	}
+
+	// this is concurrency safe
+	rate := float64(l.BytesPerSecond)
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.last.IsZero() {
+		l.tokens = rate
+	} else {
+		l.tokens = min(rate, l.tokens+now.Sub(l.last).Seconds()*rate)
+	}
+	l.last = now
+
+	// reserve the bytes, so that concurrent transfers queue up
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transferTracker tracks the progress of a single object transfer.
+type transferTracker struct {
+	container string
+	object    string
+	total     int64
+	progress  ProgressFunc
+	limiter   *BandwidthLimiter
+
+	mu    sync.Mutex
+	bytes int64
+}
+
+func newTransferTracker(container, object string, total int64, progress ProgressFunc, limiter *BandwidthLimiter) *transferTracker {
+	return &transferTracker{
+		container: container,
+		object:    object,
+		total:     total,
+		progress:  progress,
+		limiter:   limiter,
+	}
+}
+
+// add adds n transferred bytes of a segment. n is negative, when a segment
+// is transferred again.
+func (t *transferTracker) add(segment int, segmentBytes, n int64) {
+	if t == nil || t.progress == nil || n == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.bytes += n
+	t.progress(Progress{
+		Container:    t.container,
+		Object:       t.object,
+		Segment:      segment,
+		SegmentBytes: segmentBytes,
+		Bytes:        t.bytes,
+		Total:        t.total,
+	})
+}
+
+// reader wraps r to report the progress of a segment and to limit its
+// bandwidth. The returned reader implements io.Seeker, if r does.
+func (t *transferTracker) reader(ctx context.Context, r io.Reader, segment int) io.Reader {
+	if t == nil || (t.progress == nil && t.limiter.chunkSize() == 0) {
+		return r
+	}
+
+	tr := &transferReader{
+		ctx:     ctx,
+		r:       r,
+		tracker: t,
+		segment: segment,
+	}
+
+	if _, ok := r.(io.Seeker); ok {
+		return &transferReadSeeker{tr}
+	}
+
+	return tr
+}
+
+// transferReader reports the progress of a segment transfer and limits its
+// bandwidth.
+type transferReader struct {
+	ctx     context.Context
+	r       io.Reader
+	tracker *transferTracker
+	segment int
+	n       int64
+}
+
+func (r *transferReader) Read(p []byte) (int, error) {
+	if chunk := r.tracker.limiter.chunkSize(); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if err := r.tracker.limiter.wait(r.ctx, n); err != nil {
+			return 0, err
+		}
+
+		r.n += int64(n)
+		r.tracker.add(r.segment, r.n, int64(n))
+	}
+
+	return n, err
+}
+
+// rollback removes the reported bytes of a failed transfer.
+func (r *transferReader) rollback() {
+	r.tracker.add(r.segment, 0, -r.n)
+	r.n = 0
+}
+
+// transferReadSeeker is a transferReader, which can be rewound, e.g. when a
+// request is retried.
+type transferReadSeeker struct {
+	*transferReader
+}
+
+func (r *transferReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.(io.Seeker).Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+
+	if pos != r.n {
+		r.tracker.add(r.segment, pos, pos-r.n)
+		r.n = pos
+	}
+
+	return pos, nil
+}
+
+// rollbackTransfer removes the reported bytes of a failed transfer, when r
+// has been returned by transferTracker.reader.
+func rollbackTransfer(r io.Reader) {
+	switch v := r.(type) {
+	case *transferReader:
+		v.rollback()
+	case *transferReadSeeker:
+		v.rollback()
+	}
+}
+
+// transferReadCloser is a transferReader, which closes the underlying body.
+type transferReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -4,13 +4,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
@@ -20,6 +20,9 @@ import (
 
 // UploadOpts represents options used for uploading an object.
 type UploadOpts struct {
+	// BandwidthLimiter limits the bandwidth of the upload.
+	BandwidthLimiter *BandwidthLimiter
+
 	// Changed will prevent an upload if the mtime and size of the source
 	// and destination objects are the same.
 	Changed bool
@@ -45,6 +48,9 @@ type UploadOpts struct {
 	// Path is a local filesystem path of an object to be uploaded.
 	Path string
 
+	// Progress is called, when a part of the object has been uploaded.
+	Progress ProgressFunc
+
 	// Segment container is a custom container name to store object segments.
 	// If one is not specified, then "containerName_segments" will be used.
 	SegmentContainer string
@@ -53,12 +59,29 @@ type UploadOpts struct {
 	// pieces (segments) of this size.
 	SegmentSize int64
 
+	// SegmentConcurrency is the number of segments uploaded in parallel. When
+	// uploading from Content, up to this number of segments are read ahead
+	// into memory. If zero, segments are uploaded sequentially.
+	SegmentConcurrency int
+
+	// SegmentRetries is the number of times the upload of a segment is
+	// retried, before the upload fails.
+	SegmentRetries int
+
+	// Resume will reuse the segments of a previous upload of the same local
+	// file, e.g. an upload which failed before its manifest was created.
+	// The segments are reused when their size and etag match the
+	// corresponding range of the file.
+	Resume bool
+
 	// SkipIdentical is a more thorough check than "Changed". It will compare
 	// the md5sum/etag of the object as a comparison.
 	SkipIdentical bool
 
 	// StoragePolicy represents a storage policy of where the object should be
-	// uploaded.
+	// uploaded. Containers are created with this policy and an error is
+	// returned, if an existing container has a different one. If empty, the
+	// segment container is created with the policy of the container.
 	StoragePolicy string
 
 	// UseSLO will have the object uploaded using Static Large Object support.
@@ -78,7 +101,6 @@ type originalObject struct {
 type uploadSegmentOpts struct {
 	Checksum         bool
 	ContainerName    string
-	Content          io.Reader
 	Path             string
 	ObjectName       string
 	SegmentContainer string
@@ -86,6 +108,7 @@ type uploadSegmentOpts struct {
 	SegmentSize      int64
 	SegmentStart     int64
 	SegmentIndex     int
+	Tracker          *transferTracker
 }
 
 // uploadSegmentResult is an internal structure that represents the result
@@ -113,15 +136,13 @@ type sloManifest struct {
 	Path      string `json:"path"`
 	ETag      string `json:"etag"`
 	SizeBytes int64  `json:"size_bytes"`
+	Range     string `json:"range,omitempty"`
 }
 
 // Upload uploads a single object to swift.
 //
 // https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1371
 func Upload(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, opts *UploadOpts) (*UploadResult, error) {
-	var sourceFileInfo os.FileInfo
-	origObject := new(originalObject)
-
 	if opts.Path != "" && opts.Content != nil {
 		return nil, fmt.Errorf("only one of Path and Content can be used")
 	}
@@ -139,15 +160,64 @@ func Upload(ctx context.Context, client *gophercloud.ServiceClient, containerNam
 		objectName = string(objectName[:1])
 	}
 
+	if err := createContainers(ctx, client, containerName, opts); err != nil {
+		return nil, err
+	}
+
+	return upload(ctx, client, containerName, objectName, opts)
+}
+
+// createContainers creates the container and the segment container of an
+// upload, if they do not exist yet. The default segment container is set,
+// when a segment size is specified.
+func createContainers(ctx context.Context, client *gophercloud.ServiceClient, containerName string, opts *UploadOpts) error {
+	// Create the container, unless it exists. If a storage policy was
+	// specified, create the container with that policy.
+	containerPolicy, err := createContainer(ctx, client, containerName, opts.StoragePolicy)
+	if err != nil {
+		return fmt.Errorf("error creating container %s: %s", containerName, err)
+	}
+	if opts.StoragePolicy != "" && containerPolicy != opts.StoragePolicy {
+		return fmt.Errorf("container %s has storage policy %s instead of %s", containerName, containerPolicy, opts.StoragePolicy)
+	}
+
+	// If a segment size was specified, then the object will most likely
+	// be broken up into segments.
+	if opts.SegmentSize != 0 {
+		// First determine what the segment container will be called.
+		if opts.SegmentContainer == "" {
+			opts.SegmentContainer = containerName + "_segments"
+		}
+
+		// Then create the segment container.
+		// Create the segment container in either the specified policy or the same
+		// policy as the above container.
+		segmentPolicy := opts.StoragePolicy
+		if segmentPolicy == "" {
+			segmentPolicy = containerPolicy
+		}
+
+		policy, err := createContainer(ctx, client, opts.SegmentContainer, segmentPolicy)
+		if err != nil {
+			return fmt.Errorf("error creating segment container %s: %s", opts.SegmentContainer, err)
+		}
+		if opts.StoragePolicy != "" && policy != opts.StoragePolicy {
+			return fmt.Errorf("segment container %s has storage policy %s instead of %s", opts.SegmentContainer, policy, opts.StoragePolicy)
+		}
+	}
+
+	return nil
+}
+
+// upload uploads a single object to an existing container.
+func upload(ctx context.Context, client *gophercloud.ServiceClient, containerName, objectName string, opts *UploadOpts) (*UploadResult, error) {
+	var sourceFileInfo os.FileInfo
+	origObject := new(originalObject)
+
 	if len(opts.Metadata) == 0 {
 		opts.Metadata = make(map[string]string)
 	}
 
-	// Try to create the container, but ignore any errors.
-	// TODO: add X-Storage-Policy to Gophercloud.
-	// If a storage policy was specified, create the container with that policy.
-	containers.Create(ctx, client, containerName, nil)
-
 	// Check and see if the object being requested already exists.
 	objectResult := objects.Get(ctx, client, containerName, objectName, nil)
 	if objectResult.Err != nil {
@@ -186,31 +256,9 @@ func Upload(ctx context.Context, client *gophercloud.ServiceClient, containerNam
 		sourceFileInfo = fileInfo
 
 		// Format the file's mtime in the same format used by python-swiftclient.
-		v := fileInfo.ModTime().UnixNano()
-		mtime := fmt.Sprintf("%.6f", float64(v)/1000000000)
-		opts.Metadata["Mtime"] = mtime
+		opts.Metadata["Mtime"] = formatMtime(fileInfo.ModTime())
 	} else {
-		v := time.Now().UnixNano()
-		mtime := fmt.Sprintf("%.6f", float64(v)/1000000000)
-		opts.Metadata["Mtime"] = mtime
-	}
-
-	// If a segment size was specified, then the object will most likely
-	// be broken up into segments.
-	if opts.SegmentSize != 0 {
-		// First determine what the segment container will be called.
-		if opts.SegmentContainer == "" {
-			opts.SegmentContainer = containerName + "_segments"
-		}
-
-		// Then create the segment container.
-		// TODO: add X-Storage-Policy to Gophercloud.
-		// Create the segment container in either the specified policy or the same
-		// policy as the above container.
-		res := containers.Create(ctx, client, opts.SegmentContainer, nil)
-		if res.Err != nil {
-			return nil, fmt.Errorf("error creating segment container %s: %s", opts.SegmentContainer, res.Err)
-		}
+		opts.Metadata["Mtime"] = formatMtime(time.Now())
 	}
 
 	// If an io.Reader (streaming) was specified...
@@ -238,6 +286,29 @@ func Upload(ctx context.Context, client *gophercloud.ServiceClient, containerNam
 	return uploadObject(ctx, client, containerName, objectName, opts, origObject, sourceFileInfo)
 }
 
+// createContainer creates a container with the specified storage policy, if
+// it does not exist yet. It returns the storage policy of the container.
+func createContainer(ctx context.Context, client *gophercloud.ServiceClient, containerName, storagePolicy string) (string, error) {
+	headers, err := containers.Get(ctx, client, containerName, nil).Extract()
+	if err == nil {
+		return headers.StoragePolicy, nil
+	}
+	if !gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+		return "", err
+	}
+
+	createOpts := containers.CreateOpts{
+		StoragePolicy: storagePolicy,
+	}
+
+	res := containers.Create(ctx, client, containerName, createOpts)
+	if res.Err != nil {
+		return "", res.Err
+	}
+
+	return storagePolicy, nil
+}
+
 // createDirMarker will create a pseudo-directory in Swift.
 //
 // https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1656
@@ -332,6 +403,13 @@ func uploadObject(
 	// newSLOManifestPaths is a list of the new object segment's manifest paths.
 	var newSLOManifestPaths []string
 
+	// tracker reports the progress and limits the bandwidth of the upload.
+	total := int64(-1)
+	if sourceFileInfo != nil {
+		total = sourceFileInfo.Size()
+	}
+	tracker := newTransferTracker(containerName, objectName, total, opts.Progress, opts.BandwidthLimiter)
+
 	if origObject != nil {
 		origHeaders := origObject.headers
 		origMetadata := origObject.metadata
@@ -415,28 +493,42 @@ func uploadObject(
 
 	// Segment upload
 	if opts.Path != "" && opts.SegmentSize > 0 && (sourceFileInfo.Size() > opts.SegmentSize) {
-		var uploadSegmentResults []uploadSegmentResult
 		uploadResult.LargeObject = true
 
 		var segStart int64
-		var segIndex int
 		fSize := sourceFileInfo.Size()
-		segSize := opts.SegmentSize
 
-		for segStart < fSize {
-			var segName string
+		// The segment names are deterministic for a given file, so the
+		// segments of a previous upload can be found by their prefix.
+		var segPrefix string
+		if opts.UseSLO {
+			segPrefix = fmt.Sprintf("%s/slo/%s/%d/%d/",
+				objectName, opts.Metadata["Mtime"], fSize, opts.SegmentSize)
+		} else {
+			segPrefix = fmt.Sprintf("%s/%s/%d/%d/",
+				objectName, opts.Metadata["Mtime"], fSize, opts.SegmentSize)
+		}
+
+		var existingSegments map[string]objects.Object
+		if opts.Resume {
+			var err error
+			existingSegments, err = listSegments(ctx, client, opts.SegmentContainer, segPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("error listing existing segments of %s/%s: %s", containerName, objectName, err)
+			}
+		}
 
+		next := func(segIndex int) (uploadSegmentFunc, error) {
+			if segStart >= fSize {
+				return nil, nil
+			}
+
+			segSize := opts.SegmentSize
 			if segStart+segSize > fSize {
 				segSize = fSize - segStart
 			}
 
-			if opts.UseSLO {
-				segName = fmt.Sprintf("%s/slo/%s/%d/%d/%08d",
-					objectName, opts.Metadata["Mtime"], fSize, opts.SegmentSize, segIndex)
-			} else {
-				segName = fmt.Sprintf("%s/%s/%d/%d/%08d",
-					objectName, opts.Metadata["Mtime"], fSize, opts.SegmentSize, segIndex)
-			}
+			segName := fmt.Sprintf("%s%08d", segPrefix, segIndex)
 
 			uso := &uploadSegmentOpts{
 				Checksum:         opts.Checksum,
@@ -447,17 +539,32 @@ func uploadObject(
 				SegmentName:      segName,
 				SegmentSize:      segSize,
 				SegmentStart:     segStart,
+				Tracker:          tracker,
 			}
 
-			result, err := uploadSegment(ctx, client, uso)
-			if err != nil {
-				return nil, err
-			}
+			segStart += segSize
 
-			uploadSegmentResults = append(uploadSegmentResults, *result)
+			existing, ok := existingSegments[segName]
 
-			segIndex += 1
-			segStart += segSize
+			return func(ctx context.Context) (*uploadSegmentResult, error) {
+				if ok && existing.Bytes == uso.SegmentSize {
+					result, err := reuseSegment(uso, existing)
+					if err != nil {
+						return nil, err
+					}
+					if result != nil {
+						tracker.add(segIndex, result.Size, result.Size)
+						return result, nil
+					}
+				}
+
+				return uploadSegment(ctx, client, uso)
+			}, nil
+		}
+
+		uploadSegmentResults, err := uploadSegments(ctx, opts.SegmentConcurrency, opts.SegmentRetries, next)
+		if err != nil {
+			return nil, err
 		}
 
 		if opts.UseSLO {
@@ -479,9 +586,8 @@ func uploadObject(
 				newSLOManifestPaths = append(newSLOManifestPaths, segPath)
 			}
 		} else {
-			newObjectManifest := fmt.Sprintf("%s/%s/%s/%d/%d/",
-				url.QueryEscape(opts.SegmentContainer), url.QueryEscape(objectName),
-				opts.Metadata["Mtime"], fSize, opts.SegmentSize)
+			newObjectManifest := formatObjectManifest(opts.SegmentContainer, fmt.Sprintf("%s/%s/%d/%d/",
+				objectName, opts.Metadata["Mtime"], fSize, opts.SegmentSize))
 
 			if oldObjectManifest != "" {
 				if strings.TrimSuffix(oldObjectManifest, "/") == strings.TrimSuffix(newObjectManifest, "/") {
@@ -503,42 +609,56 @@ func uploadObject(
 		}
 	} else if opts.UseSLO && opts.SegmentSize > 0 && opts.Path == "" {
 		// Streaming segment upload
-		var segIndex int
-		var uploadSegmentResults []uploadSegmentResult
+		var complete bool
+
+		next := func(segIndex int) (uploadSegmentFunc, error) {
+			if complete {
+				return nil, nil
+			}
+
+			// The segments have to be read sequentially, but they can be
+			// uploaded while the next ones are read ahead.
+			data, err := readStreamingSegment(opts.Content, opts.SegmentSize)
+			if err != nil {
+				return nil, fmt.Errorf("error reading segment %d of %s/%s: %s", segIndex, containerName, objectName, err)
+			}
+
+			complete = int64(len(data)) < opts.SegmentSize
+			if len(data) == 0 {
+				return nil, nil
+			}
 
-		for {
 			segName := fmt.Sprintf("%s/slo/%s/%d/%08d",
 				objectName, opts.Metadata["Mtime"], opts.SegmentSize, segIndex)
 
 			// Checksum is not passed here because it's always done during streaming.
 			uso := &uploadSegmentOpts{
-				Content:          opts.Content,
 				ContainerName:    containerName,
 				ObjectName:       objectName,
 				SegmentContainer: opts.SegmentContainer,
 				SegmentIndex:     segIndex,
 				SegmentName:      segName,
 				SegmentSize:      opts.SegmentSize,
+				Tracker:          tracker,
 			}
 
-			uploadSegmentResult, err := uploadStreamingSegment(ctx, client, uso)
-			if err != nil {
-				return nil, fmt.Errorf("error uploading segment %d of %s/%s: %s", segIndex, containerName, objectName, err)
-			}
-
-			if !uploadSegmentResult.Success {
-				return nil, fmt.Errorf("problem uploading segment %d of %s/%s", segIndex, containerName, objectName)
-			}
+			return func(ctx context.Context) (*uploadSegmentResult, error) {
+				uploadSegmentResult, err := uploadStreamingSegment(ctx, client, uso, data)
+				if err != nil {
+					return nil, fmt.Errorf("error uploading segment %d of %s/%s: %s", segIndex, containerName, objectName, err)
+				}
 
-			if uploadSegmentResult.Size != 0 {
-				uploadSegmentResults = append(uploadSegmentResults, *uploadSegmentResult)
-			}
+				if !uploadSegmentResult.Success {
+					return nil, fmt.Errorf("problem uploading segment %d of %s/%s", segIndex, containerName, objectName)
+				}
 
-			if uploadSegmentResult.Complete {
-				break
-			}
+				return uploadSegmentResult, nil
+			}, nil
+		}
 
-			segIndex += 1
+		uploadSegmentResults, err := uploadSegments(ctx, opts.SegmentConcurrency, opts.SegmentRetries, next)
+		if err != nil {
+			return nil, err
 		}
 
 		if len(uploadSegmentResults) > 0 {
@@ -649,8 +769,13 @@ func uploadObject(
 			noETag = true
 		}
 
+		if opts.Path == "" && contentLength > 0 {
+			tracker.total = contentLength
+		}
+		content := tracker.reader(ctx, reader, -1)
+
 		createOpts := objects.CreateOpts{
-			Content:       reader,
+			Content:       content,
 			ContentLength: contentLength,
 			Metadata:      opts.Metadata,
 			ETag:          eTag,
@@ -659,6 +784,7 @@ func uploadObject(
 
 		createHeader, err := objects.Create(ctx, client, containerName, objectName, createOpts).Extract()
 		if err != nil {
+			rollbackTransfer(content)
 			return nil, err
 		}
 
@@ -675,9 +801,10 @@ func uploadObject(
 		if oldObjectManifest != "" {
 			var oldObjects []string
 
-			parts := strings.SplitN(oldObjectManifest, "/", 2)
-			sContainer := parts[0]
-			sPrefix := parts[1]
+			sContainer, sPrefix, err := parseObjectManifest(oldObjectManifest)
+			if err != nil {
+				return nil, err
+			}
 
 			sPrefix = strings.TrimRight(sPrefix, "/") + "/"
 
@@ -754,25 +881,12 @@ func uploadSLOManifest(ctx context.Context, client *gophercloud.ServiceClient, o
 		manifest = append(manifest, m)
 	}
 
-	b, err := json.Marshal(manifest)
-	if err != nil {
-		return err
-	}
-
 	createOpts := objects.CreateOpts{
-		Content:           strings.NewReader(string(b)),
-		ContentType:       "application/json",
-		Metadata:          opts.Metadata,
-		MultipartManifest: "put",
-		NoETag:            true,
-	}
-
-	res := objects.Create(ctx, client, opts.ContainerName, opts.ObjectName, createOpts)
-	if res.Err != nil {
-		return res.Err
+		ContentType: "application/json",
+		Metadata:    opts.Metadata,
 	}
 
-	return nil
+	return putSLOManifest(ctx, client, opts.ContainerName, opts.ObjectName, manifest, createOpts)
 }
 
 // https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1719
@@ -806,21 +920,25 @@ func uploadSegment(ctx context.Context, client *gophercloud.ServiceClient, opts
 		noETag = true
 	}
 
+	content := opts.Tracker.reader(ctx, bytes.NewReader(buf[:n]), opts.SegmentIndex)
+
 	createOpts := objects.CreateOpts{
 		ContentLength: int64(n),
 		ContentType:   "application/swiftclient-segment",
-		Content:       bytes.NewReader(buf),
+		Content:       content,
 		ETag:          eTag,
 		NoETag:        noETag,
 	}
 
 	createHeader, err := objects.Create(ctx, client, opts.SegmentContainer, opts.SegmentName, createOpts).Extract()
 	if err != nil {
+		rollbackTransfer(content)
 		return nil, err
 	}
 
 	if opts.Checksum {
 		if createHeader.ETag != eTag {
+			rollbackTransfer(content)
 			err := fmt.Errorf("segment %d: upload verification failed: md5 mismatch, local %s != remote %s", opts.SegmentIndex, eTag, createHeader.ETag)
 			return nil, err
 		}
@@ -836,21 +954,76 @@ func uploadSegment(ctx context.Context, client *gophercloud.ServiceClient, opts
 	return result, nil
 }
 
-// uploadStreamingSegment will upload an object segment from a streaming source.
-//
-// https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1846
-func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClient, opts *uploadSegmentOpts) (*uploadSegmentResult, error) {
-	var result uploadSegmentResult
+// listSegments returns the existing segments, which names start with the
+// specified prefix.
+func listSegments(ctx context.Context, client *gophercloud.ServiceClient, segmentContainer, prefix string) (map[string]objects.Object, error) {
+	listOpts := objects.ListOpts{
+		Prefix: prefix,
+	}
 
-	// Checksum is always done when streaming.
-	hash := md5.New()
-	buf := bytes.NewBuffer([]byte{})
-	n, err := io.CopyN(io.MultiWriter(hash, buf), opts.Content, opts.SegmentSize)
+	allPages, err := objects.List(client, segmentContainer, listOpts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allObjects, err := objects.ExtractInfo(allPages)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make(map[string]objects.Object, len(allObjects))
+	for _, object := range allObjects {
+		segments[object.Name] = object
+	}
+
+	return segments, nil
+}
+
+// reuseSegment compares an existing segment with the corresponding range of
+// the local file. It returns the result of the segment, if they match, or
+// nil, if the segment has to be uploaded.
+func reuseSegment(opts *uploadSegmentOpts, existing objects.Object) (*uploadSegmentResult, error) {
+	eTag, err := fileRangeMD5Sum(opts.Path, opts.SegmentStart, opts.SegmentSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if eTag != existing.Hash {
+		return nil, nil
+	}
+
+	result := &uploadSegmentResult{
+		ETag:     existing.Hash,
+		Index:    opts.SegmentIndex,
+		Location: fmt.Sprintf("/%s/%s", opts.SegmentContainer, opts.SegmentName),
+		Size:     opts.SegmentSize,
+	}
+
+	return result, nil
+}
+
+// readStreamingSegment reads the next segment of a streaming source into
+// memory, so that it can be uploaded concurrently and retried.
+func readStreamingSegment(content io.Reader, segmentSize int64) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, segmentSize))
+	_, err := io.CopyN(buf, content, segmentSize)
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
 
-	localChecksum := fmt.Sprintf("%x", hash.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+// uploadStreamingSegment will upload an object segment, which has been read
+// from a streaming source.
+//
+// https://github.com/openstack/python-swiftclient/blob/e65070964c7b1e04119c87e5f344d39358780d18/swiftclient/service.py#L1846
+func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClient, opts *uploadSegmentOpts, data []byte) (*uploadSegmentResult, error) {
+	var result uploadSegmentResult
+
+	// Checksum is always done when streaming.
+	n := int64(len(data))
+	localChecksum := fmt.Sprintf("%x", md5.Sum(data))
 
 	if n == 0 {
 		result.Complete = true
@@ -860,8 +1033,10 @@ func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClie
 		return &result, nil
 	}
 
+	content := opts.Tracker.reader(ctx, bytes.NewReader(data), opts.SegmentIndex)
+
 	createOpts := objects.CreateOpts{
-		Content:       bytes.NewReader(buf.Bytes()),
+		Content:       content,
 		ContentLength: n,
 		ETag:          localChecksum,
 		// TODO
@@ -871,6 +1046,7 @@ func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClie
 	if opts.SegmentIndex == 0 && n < opts.SegmentSize {
 		res := objects.Create(ctx, client, opts.ContainerName, opts.ObjectName, createOpts)
 		if res.Err != nil {
+			rollbackTransfer(content)
 			return nil, res.Err
 		}
 
@@ -878,6 +1054,7 @@ func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClie
 	} else {
 		res := objects.Create(ctx, client, opts.SegmentContainer, opts.SegmentName, createOpts)
 		if res.Err != nil {
+			rollbackTransfer(content)
 			return nil, res.Err
 		}
 
@@ -892,3 +1069,65 @@ func uploadStreamingSegment(ctx context.Context, client *gophercloud.ServiceClie
 
 	return &result, nil
 }
+
+// uploadSegmentFunc uploads a single segment.
+type uploadSegmentFunc func(ctx context.Context) (*uploadSegmentResult, error)
+
+// uploadSegments uploads segments using up to concurrency workers. The next
+// function returns the upload function of the segment with the specified
+// index, or nil, when there are no more segments. It is called once a
+// worker is available, so that read-ahead segments are bounded by the
+// concurrency. Each segment upload is retried up to retries times. The
+// results are returned in the order of the segments.
+func uploadSegments(ctx context.Context, concurrency, retries int, next func(segIndex int) (uploadSegmentFunc, error)) ([]uploadSegmentResult, error) {
+	var (
+		mu      sync.Mutex
+		results []uploadSegmentResult
+	)
+
+	err := runConcurrently(ctx, concurrency, func(segIndex int) (func(ctx context.Context) error, error) {
+		upload, err := next(segIndex)
+		if err != nil || upload == nil {
+			return nil, err
+		}
+
+		return func(ctx context.Context) error {
+			result, err := retrySegment(ctx, retries, upload)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			results = append(results, *result)
+			mu.Unlock()
+
+			return nil
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+
+	return results, nil
+}
+
+// retrySegment calls the upload function up to retries+1 times, with a
+// linearly increasing delay between the attempts.
+func retrySegment(ctx context.Context, retries int, upload uploadSegmentFunc) (*uploadSegmentResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := upload(ctx)
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return result, err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt+1) * segmentRetryDelay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
@@ -2,16 +2,25 @@ package objects
 
 import (
 	"bufio"
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	emptyETag  = "d41d8cd98f00b204e9800998ecf8427e"
 	diskBuffer = 65536
+
+	// segmentRetryDelay is the delay before the first retry of a failed
+	// segment upload.
+	segmentRetryDelay = time.Second
 )
 
 var (
@@ -33,6 +42,22 @@ func ContainerPartition(containerName string) (string, string) {
 	return containerName, pseudoFolder
 }
 
+// fileRangeMD5Sum returns the md5sum of the specified range of a file.
+func fileRangeMD5Sum(filename string, start, size int64) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(f, start, size)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
 // https://github.com/holys/checksum/blob/master/md5/md5.go
 func FileMD5Sum(filename string) (string, error) {
 	if _, err := os.Stat(filename); err != nil {
@@ -63,7 +88,122 @@ func FileMD5Sum(filename string) (string, error) {
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
+// formatMtime formats a modification time in the format of the Mtime
+// metadata used by python-swiftclient.
+func formatMtime(t time.Time) string {
+	return fmt.Sprintf("%.6f", float64(t.UnixNano())/1000000000)
+}
+
+// parseMtime parses the Mtime metadata.
+func parseMtime(v string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(v, ".")
+
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var ns int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		ns, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Unix(s, ns), nil
+}
+
+// forEachConcurrently calls fn for the indexes from 0 to n-1 using up to
+// concurrency goroutines. It stops at the first error and returns it.
+func forEachConcurrently(ctx context.Context, concurrency, n int, fn func(ctx context.Context, i int) error) error {
+	return runConcurrently(ctx, concurrency, func(i int) (func(ctx context.Context) error, error) {
+		if i >= n {
+			return nil, nil
+		}
+		return func(ctx context.Context) error {
+			return fn(ctx, i)
+		}, nil
+	})
+}
+
+// runConcurrently runs the tasks returned by next using up to concurrency
+// goroutines. next is called sequentially with increasing indexes, once a
+// goroutine is available, and returns nil, when there are no more tasks.
+// It stops at the first error and returns it.
+func runConcurrently(ctx context.Context, concurrency int, next func(i int) (func(ctx context.Context) error, error)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	setErr := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	workers := make(chan struct{}, concurrency)
+	for i := 0; ; i++ {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		task, err := next(i)
+		if err != nil || task == nil {
+			<-workers
+			if err != nil {
+				setErr(err)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			if err := task(ctx); err != nil {
+				setErr(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
 func GetContentType(ct string) string {
 	v := strings.SplitN(ct, ";", 2)
 	return v[0]
 }
+
+// escapeObjectName escapes the path segments of an object name.
+func escapeObjectName(objectName string) string {
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}